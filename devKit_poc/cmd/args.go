@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// coerceCallArgs converts a CallSpec's JSON-decoded Args into the
+// Go-native values inputs.Pack actually requires: JSON numbers arrive
+// as json.Number (the task payload is decoded with UseNumber, so
+// uint256/int256-range numbers don't lose precision through float64),
+// addresses/bytes as plain hex strings, and so on, none of which match
+// go-ethereum's ABI packer without this step.
+func coerceCallArgs(inputs abi.Arguments, args []interface{}) ([]interface{}, error) {
+	if len(args) != len(inputs) {
+		return nil, fmt.Errorf("expected %d arguments, got %d", len(inputs), len(args))
+	}
+
+	coerced := make([]interface{}, len(args))
+	for i, input := range inputs {
+		v, err := coerceABIValue(input.Type, args[i])
+		if err != nil {
+			return nil, fmt.Errorf("argument %d (%s): %w", i, input.Name, err)
+		}
+		coerced[i] = v
+	}
+	return coerced, nil
+}
+
+// coerceABIValue converts a single JSON-decoded value to the Go type
+// abi.Type t expects, recursing into slice/array element types.
+func coerceABIValue(t abi.Type, v interface{}) (interface{}, error) {
+	switch t.T {
+	case abi.UintTy, abi.IntTy:
+		bi, err := coerceToBigInt(v)
+		if err != nil {
+			return nil, err
+		}
+		return bigIntToABIType(t, bi)
+	case abi.AddressTy:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected an address string, got %T", v)
+		}
+		if !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+		return common.HexToAddress(s), nil
+	case abi.BoolTy:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a bool, got %T", v)
+		}
+		return b, nil
+	case abi.StringTy:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %T", v)
+		}
+		return s, nil
+	case abi.BytesTy:
+		return coerceToHexBytes(v)
+	case abi.FixedBytesTy:
+		raw, err := coerceToHexBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) != t.Size {
+			return nil, fmt.Errorf("expected %d bytes, got %d", t.Size, len(raw))
+		}
+		fixed := reflect.New(t.GetType()).Elem()
+		reflect.Copy(fixed, reflect.ValueOf(raw))
+		return fixed.Interface(), nil
+	case abi.SliceTy, abi.ArrayTy:
+		return coerceABISequence(t, v)
+	default:
+		// Tuples and anything else unsupported by this oracle: pass the
+		// JSON value through unchanged rather than guessing its shape.
+		return v, nil
+	}
+}
+
+// coerceABISequence converts a JSON array into the Go slice/array type
+// abi.Pack expects for a dynamic ("type[]") or fixed-size ("type[N]")
+// array argument, recursively coercing each element against t.Elem.
+func coerceABISequence(t abi.Type, v interface{}) (interface{}, error) {
+	raw, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON array, got %T", v)
+	}
+	if t.T == abi.ArrayTy && len(raw) != t.Size {
+		return nil, fmt.Errorf("expected %d elements, got %d", t.Size, len(raw))
+	}
+
+	elemType := t.Elem.GetType()
+	var seq reflect.Value
+	if t.T == abi.ArrayTy {
+		seq = reflect.New(reflect.ArrayOf(t.Size, elemType)).Elem()
+	} else {
+		seq = reflect.MakeSlice(reflect.SliceOf(elemType), len(raw), len(raw))
+	}
+
+	for i, e := range raw {
+		coercedElem, err := coerceABIValue(*t.Elem, e)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		seq.Index(i).Set(reflect.ValueOf(coercedElem))
+	}
+	return seq.Interface(), nil
+}
+
+// coerceToBigInt converts a JSON-decoded number into a *big.Int. Numbers
+// normally arrive as json.Number (the task payload is decoded with
+// UseNumber to preserve uint256-range precision) or as a decimal/hex-
+// prefixed string; the float64 case only covers values constructed
+// in-process rather than decoded from a task payload.
+func coerceToBigInt(v interface{}) (*big.Int, error) {
+	switch val := v.(type) {
+	case float64:
+		bi, _ := big.NewFloat(val).Int(nil)
+		return bi, nil
+	case json.Number:
+		bi, ok := new(big.Int).SetString(val.String(), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", val)
+		}
+		return bi, nil
+	case string:
+		s, base := val, 10
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+			s, base = s[2:], 16
+		}
+		bi, ok := new(big.Int).SetString(s, base)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", val)
+		}
+		return bi, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %T to an integer", v)
+	}
+}
+
+// bigIntToABIType narrows bi to the concrete Go type t's ABI packer
+// expects. go-ethereum only uses *big.Int for integer widths above 64
+// bits (t.GetType() reports a pointer kind for those); uint8/16/32/64
+// and int8/16/32/64 must be converted to that native sized type, or
+// abi.Pack rejects a *big.Int argument outright. Shared with watch.go's
+// decodeIndexedTopic, which recovers the same integer types from a
+// topic hash and re-packs them against the event's declared type.
+func bigIntToABIType(t abi.Type, bi *big.Int) (interface{}, error) {
+	rt := t.GetType()
+	if rt.Kind() == reflect.Ptr {
+		return bi, nil
+	}
+
+	if t.T == abi.UintTy {
+		max := new(big.Int).Lsh(big.NewInt(1), uint(t.Size))
+		if bi.Sign() < 0 || bi.Cmp(max) >= 0 {
+			return nil, fmt.Errorf("value %s does not fit in uint%d", bi, t.Size)
+		}
+		return reflect.ValueOf(bi.Uint64()).Convert(rt).Interface(), nil
+	}
+
+	half := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+	min := new(big.Int).Neg(half)
+	max := new(big.Int).Sub(half, big.NewInt(1))
+	if bi.Cmp(min) < 0 || bi.Cmp(max) > 0 {
+		return nil, fmt.Errorf("value %s does not fit in int%d", bi, t.Size)
+	}
+	return reflect.ValueOf(bi.Int64()).Convert(rt).Interface(), nil
+}
+
+// coerceToHexBytes decodes a 0x-prefixed hex string into raw bytes, the
+// shape bytes/bytesN arguments are expected to arrive in from JSON.
+func coerceToHexBytes(v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a 0x-prefixed hex string, got %T", v)
+	}
+	raw, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex string %q: %w", s, err)
+	}
+	return raw, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const balanceOfABI = `[
+	{
+		"inputs": [{"internalType": "address", "name": "account", "type": "address"}],
+		"name": "balanceOf",
+		"outputs": [{"internalType": "uint256", "name": "", "type": "uint256"}],
+		"stateMutability": "view",
+		"type": "function"
+	}
+]`
+
+// TestCoerceCallArgsBalanceOf exercises the path a raw JSON task payload
+// takes: call.Args decoded by encoding/json (so the address arrives as a
+// plain string) must coerce into a common.Address before Pack, since
+// go-ethereum's ABI packer rejects a bare string for an address input.
+func TestCoerceCallArgsBalanceOf(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(balanceOfABI))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	method := parsed.Methods["balanceOf"]
+
+	account := "0x0000000000000000000000000000000000000042"
+	coerced, err := coerceCallArgs(method.Inputs, []interface{}{account})
+	if err != nil {
+		t.Fatalf("coerceCallArgs returned unexpected error: %v", err)
+	}
+
+	addr, ok := coerced[0].(common.Address)
+	if !ok {
+		t.Fatalf("expected coerced arg to be common.Address, got %T", coerced[0])
+	}
+	if addr != common.HexToAddress(account) {
+		t.Fatalf("expected %s, got %s", account, addr)
+	}
+
+	if _, err := parsed.Pack("balanceOf", coerced...); err != nil {
+		t.Fatalf("Pack failed after coercion: %v", err)
+	}
+
+	if _, err := parsed.Pack("balanceOf", account); err == nil {
+		t.Fatalf("expected Pack to fail against the raw, uncoerced JSON string")
+	}
+}
+
+func TestCoerceCallArgsUint256FromJSONNumber(t *testing.T) {
+	uintType, err := abi.NewType("uint256", "", nil)
+	if err != nil {
+		t.Fatalf("failed to build uint256 type: %v", err)
+	}
+	inputs := abi.Arguments{{Name: "amount", Type: uintType}}
+
+	coerced, err := coerceCallArgs(inputs, []interface{}{float64(100)})
+	if err != nil {
+		t.Fatalf("coerceCallArgs returned unexpected error: %v", err)
+	}
+
+	if _, err := inputs.Pack(coerced...); err != nil {
+		t.Fatalf("Pack failed after coercion: %v", err)
+	}
+}
+
+// TestCoerceCallArgsUint256FromJSONPayload decodes a raw JSON payload the
+// way asMultiCallTask does, with json.Decoder.UseNumber, rather than
+// passing a float64 directly: a token-amount-sized uint256 exceeds
+// float64's exact integer range (2^53) and must survive the round trip
+// without losing precision.
+func TestCoerceCallArgsUint256FromJSONPayload(t *testing.T) {
+	uintType, err := abi.NewType("uint256", "", nil)
+	if err != nil {
+		t.Fatalf("failed to build uint256 type: %v", err)
+	}
+	inputs := abi.Arguments{{Name: "amount", Type: uintType}}
+
+	const want = "123456789012345678"
+	dec := json.NewDecoder(bytes.NewReader([]byte(`[` + want + `]`)))
+	dec.UseNumber()
+	var args []interface{}
+	if err := dec.Decode(&args); err != nil {
+		t.Fatalf("failed to decode JSON payload: %v", err)
+	}
+
+	coerced, err := coerceCallArgs(inputs, args)
+	if err != nil {
+		t.Fatalf("coerceCallArgs returned unexpected error: %v", err)
+	}
+
+	got, ok := coerced[0].(*big.Int)
+	if !ok {
+		t.Fatalf("expected coerced arg to be *big.Int, got %T", coerced[0])
+	}
+	wantInt, _ := new(big.Int).SetString(want, 10)
+	if got.Cmp(wantInt) != 0 {
+		t.Fatalf("expected %s, got %s", wantInt, got)
+	}
+}
+
+// TestCoerceCallArgsNarrowUint is the regression test for the bug where
+// coerceToBigInt's *big.Int result was passed straight to Pack: that
+// fails for any integer type narrower than 256 bits, since go-ethereum's
+// ABI packer requires a native uint8/16/32/64 for those, not a pointer.
+func TestCoerceCallArgsNarrowUint(t *testing.T) {
+	uintType, err := abi.NewType("uint8", "", nil)
+	if err != nil {
+		t.Fatalf("failed to build uint8 type: %v", err)
+	}
+	inputs := abi.Arguments{{Name: "decimals", Type: uintType}}
+
+	coerced, err := coerceCallArgs(inputs, []interface{}{json.Number("18")})
+	if err != nil {
+		t.Fatalf("coerceCallArgs returned unexpected error: %v", err)
+	}
+
+	got, ok := coerced[0].(uint8)
+	if !ok {
+		t.Fatalf("expected coerced arg to be uint8, got %T", coerced[0])
+	}
+	if got != 18 {
+		t.Fatalf("expected 18, got %d", got)
+	}
+
+	if _, err := inputs.Pack(coerced...); err != nil {
+		t.Fatalf("Pack failed after coercion: %v", err)
+	}
+}
+
+// TestCoerceCallArgsNarrowUintOverflow ensures a value that doesn't fit
+// the declared width is rejected rather than silently truncated.
+func TestCoerceCallArgsNarrowUintOverflow(t *testing.T) {
+	uintType, err := abi.NewType("uint8", "", nil)
+	if err != nil {
+		t.Fatalf("failed to build uint8 type: %v", err)
+	}
+	inputs := abi.Arguments{{Name: "decimals", Type: uintType}}
+
+	if _, err := coerceCallArgs(inputs, []interface{}{json.Number("256")}); err == nil {
+		t.Fatalf("expected an error for a uint8 argument that overflows")
+	}
+}
+
+func TestCoerceCallArgsArgumentCountMismatch(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(balanceOfABI))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	method := parsed.Methods["balanceOf"]
+
+	if _, err := coerceCallArgs(method.Inputs, nil); err == nil {
+		t.Fatalf("expected an error for a missing required argument")
+	}
+}
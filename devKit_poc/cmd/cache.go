@@ -0,0 +1,104 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cacheEntry is the value stored in resultCache, wrapping a callResult
+// with the time it expires at.
+type cacheEntry struct {
+	key       string
+	result    callResult
+	expiresAt time.Time
+	element   *list.Element
+}
+
+// resultCache is a fixed-capacity LRU cache with a per-entry TTL,
+// keyed by a string cacheKeyFor builds from (chainId, address, selector,
+// args, requested block). It exists because realistic AVS load repeats
+// the same (address, block) read many times within a short window.
+type resultCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*cacheEntry
+	order    *list.List // front = most recently used
+}
+
+// newResultCache builds a cache with the given capacity and TTL. A
+// capacity or ttl of zero disables caching entirely (get always
+// misses, put is a no-op).
+func newResultCache(capacity int, ttl time.Duration) *resultCache {
+	return &resultCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*cacheEntry),
+		order:    list.New(),
+	}
+}
+
+func (c *resultCache) enabled() bool {
+	return c.capacity > 0 && c.ttl > 0
+}
+
+// get returns a cached result for key if present and unexpired,
+// promoting it to most-recently-used.
+func (c *resultCache) get(key string) (callResult, bool) {
+	if !c.enabled() {
+		return callResult{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return callResult{}, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		c.removeLocked(entry)
+		return callResult{}, false
+	}
+
+	c.order.MoveToFront(entry.element)
+	return entry.result, true
+}
+
+// put stores result under key, evicting the least-recently-used entry
+// if the cache is at capacity.
+func (c *resultCache) put(key string, result callResult) {
+	if !c.enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existing, ok := c.entries[key]; ok {
+		existing.result = result
+		existing.expiresAt = time.Now().Add(c.ttl)
+		c.order.MoveToFront(existing.element)
+		return
+	}
+
+	entry := &cacheEntry{key: key, result: result, expiresAt: time.Now().Add(c.ttl)}
+	entry.element = c.order.PushFront(entry)
+	c.entries[key] = entry
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.removeLocked(oldest.Value.(*cacheEntry))
+	}
+}
+
+// removeLocked evicts entry from both the map and the LRU list. Caller
+// must hold c.mu.
+func (c *resultCache) removeLocked(entry *cacheEntry) {
+	delete(c.entries, entry.key)
+	c.order.Remove(entry.element)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newResultCache(2, time.Minute)
+
+	c.put("a", callResult{index: 1})
+	c.put("b", callResult{index: 2})
+
+	// Touch "a" so "b" becomes the least-recently-used entry.
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("expected a to be cached")
+	}
+
+	c.put("c", callResult{index: 3})
+
+	if _, ok := c.get("b"); ok {
+		t.Fatalf("expected b to have been evicted as least-recently-used")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("expected a to remain cached after being touched by get")
+	}
+	if _, ok := c.get("c"); !ok {
+		t.Fatalf("expected c to be cached")
+	}
+}
+
+func TestResultCacheExpiresEntriesAfterTTL(t *testing.T) {
+	c := newResultCache(10, 10*time.Millisecond)
+
+	c.put("a", callResult{index: 1})
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("expected a to be cached immediately after put")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := c.get("a"); ok {
+		t.Fatalf("expected a to have expired")
+	}
+}
+
+func TestResultCacheDisabledWhenCapacityOrTTLIsZero(t *testing.T) {
+	for _, c := range []*resultCache{
+		newResultCache(0, time.Minute),
+		newResultCache(10, 0),
+	} {
+		c.put("a", callResult{index: 1})
+		if _, ok := c.get("a"); ok {
+			t.Fatalf("expected a disabled cache to never hit")
+		}
+	}
+}
+
+func TestResultCacheConcurrentAccess(t *testing.T) {
+	c := newResultCache(16, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i%8)
+			c.put(key, callResult{index: i})
+			c.get(key)
+		}(i)
+	}
+	wg.Wait()
+}
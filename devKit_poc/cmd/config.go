@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EndpointConfig describes a single chain endpoint the worker can read
+// through: a JSON-RPC URL or an IPC socket path, never both.
+type EndpointConfig struct {
+	// Name labels this endpoint in metrics. Defaults to URL or IPCPath
+	// when left blank.
+	Name               string            `yaml:"name" json:"name"`
+	URL                string            `yaml:"url" json:"url"`
+	IPCPath            string            `yaml:"ipcPath" json:"ipcPath"`
+	Headers            map[string]string `yaml:"headers" json:"headers"`
+	InsecureSkipVerify bool              `yaml:"insecureSkipVerify" json:"insecureSkipVerify"`
+	TLSConfig          *tls.Config       `yaml:"-" json:"-"`
+}
+
+// RetryPolicy controls how the worker retries a failed upstream call
+// before giving up or falling over to the next endpoint.
+type RetryPolicy struct {
+	MaxAttempts  int           `yaml:"maxAttempts" json:"maxAttempts"`
+	InitialDelay time.Duration `yaml:"initialDelay" json:"initialDelay"`
+	MaxDelay     time.Duration `yaml:"maxDelay" json:"maxDelay"`
+}
+
+// WorkerConfig is everything NewTaskWorker needs to stand up its
+// DataSource. It is loaded from YAML (WORKER_CONFIG_FILE) or, failing
+// that, from individual env vars, so the performer can be configured
+// the same way in a container as on a laptop.
+type WorkerConfig struct {
+	Endpoints               []EndpointConfig `yaml:"endpoints" json:"endpoints"`
+	Timeout                 time.Duration    `yaml:"timeout" json:"timeout"`
+	Retry                   RetryPolicy      `yaml:"retry" json:"retry"`
+	ExpectedChainID         uint64           `yaml:"expectedChainId" json:"expectedChainId"`
+	CircuitFailureThreshold int              `yaml:"circuitFailureThreshold" json:"circuitFailureThreshold"`
+	CircuitCooldown         time.Duration    `yaml:"circuitCooldown" json:"circuitCooldown"`
+
+	// CacheSize is the max number of distinct (chainId, address,
+	// selector, args, requested block) reads kept in the result cache. Zero
+	// disables caching.
+	CacheSize int `yaml:"cacheSize" json:"cacheSize"`
+	// CacheTTL is how long a cached read stays valid. Zero disables caching.
+	CacheTTL time.Duration `yaml:"cacheTtl" json:"cacheTtl"`
+	// MetricsPort exposes Prometheus metrics on this port when non-zero.
+	MetricsPort int `yaml:"metricsPort" json:"metricsPort"`
+}
+
+// defaultWorkerConfig mirrors the previous hardcoded behavior (a single
+// local anvil endpoint) so existing dev workflows keep working without
+// any configuration at all.
+func defaultWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		Endpoints: []EndpointConfig{{URL: "http://localhost:8545"}},
+		Timeout:   5 * time.Second,
+		Retry: RetryPolicy{
+			MaxAttempts:  3,
+			InitialDelay: 200 * time.Millisecond,
+			MaxDelay:     2 * time.Second,
+		},
+		CircuitFailureThreshold: 5,
+		CircuitCooldown:         30 * time.Second,
+		CacheSize:               1024,
+		CacheTTL:                10 * time.Second,
+		MetricsPort:             9090,
+	}
+}
+
+// LoadWorkerConfig resolves the worker's configuration: a YAML file
+// named by WORKER_CONFIG_FILE takes precedence, otherwise individual
+// env vars are consulted, otherwise the local-anvil default applies.
+func LoadWorkerConfig() (WorkerConfig, error) {
+	if path := os.Getenv("WORKER_CONFIG_FILE"); path != "" {
+		return loadWorkerConfigFromYAML(path)
+	}
+	return loadWorkerConfigFromEnv(), nil
+}
+
+func loadWorkerConfigFromYAML(path string) (WorkerConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return WorkerConfig{}, fmt.Errorf("failed to read worker config %q: %w", path, err)
+	}
+
+	cfg := defaultWorkerConfig()
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return WorkerConfig{}, fmt.Errorf("failed to parse worker config %q: %w", path, err)
+	}
+	for i := range cfg.Endpoints {
+		cfg.Endpoints[i].TLSConfig = tlsConfigFromSkipVerify(cfg.Endpoints[i].InsecureSkipVerify)
+	}
+	return cfg, nil
+}
+
+// loadWorkerConfigFromEnv builds a config from WORKER_RPC_URLS (a comma
+// separated list) and a handful of scalar overrides. It never fails;
+// malformed numeric overrides just fall back to the default.
+func loadWorkerConfigFromEnv() WorkerConfig {
+	cfg := defaultWorkerConfig()
+
+	if urls := os.Getenv("WORKER_RPC_URLS"); urls != "" {
+		endpoints := make([]EndpointConfig, 0)
+		for _, u := range strings.Split(urls, ",") {
+			u = strings.TrimSpace(u)
+			if u == "" {
+				continue
+			}
+			endpoints = append(endpoints, EndpointConfig{URL: u})
+		}
+		if len(endpoints) > 0 {
+			cfg.Endpoints = endpoints
+		}
+	}
+
+	if v := os.Getenv("WORKER_IPC_PATH"); v != "" {
+		cfg.Endpoints = append(cfg.Endpoints, EndpointConfig{IPCPath: v})
+	}
+
+	if v := os.Getenv("WORKER_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Timeout = d
+		}
+	}
+
+	if v := os.Getenv("WORKER_EXPECTED_CHAIN_ID"); v != "" {
+		if id, err := strconv.ParseUint(v, 10, 64); err == nil {
+			cfg.ExpectedChainID = id
+		}
+	}
+
+	if v := os.Getenv("WORKER_CACHE_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			cfg.CacheSize = size
+		}
+	}
+
+	if v := os.Getenv("WORKER_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.CacheTTL = d
+		}
+	}
+
+	if v := os.Getenv("WORKER_METRICS_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			cfg.MetricsPort = port
+		}
+	}
+
+	return cfg
+}
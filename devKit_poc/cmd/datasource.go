@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DataSource abstracts the chain connection the performer reads through,
+// so HandleTask never depends on a concrete transport. This lets the same
+// worker run against JSON-RPC, an IPC socket, or an in-process client
+// (e.g. in tests) interchangeably.
+type DataSource interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	HeaderByHash(ctx context.Context, hash [32]byte) (*types.Header, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+	GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNumber *big.Int) (*gethclient.AccountResult, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
+	Close()
+}
+
+// JSONRPCDataSource talks to a chain over HTTP(S)/WS JSON-RPC.
+type JSONRPCDataSource struct {
+	client *ethclient.Client
+	proof  *gethclient.Client
+}
+
+// NewJSONRPCDataSource dials the given endpoint. Auth headers and TLS are
+// applied via a custom rpc.Client so callers can point this at gated
+// infra providers rather than only trust-free public endpoints.
+func NewJSONRPCDataSource(ctx context.Context, endpoint EndpointConfig) (*JSONRPCDataSource, error) {
+	opts := []rpc.ClientOption{}
+	if endpoint.TLSConfig != nil {
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: endpoint.TLSConfig}}
+		opts = append(opts, rpc.WithHTTPClient(httpClient))
+	}
+	if len(endpoint.Headers) > 0 {
+		hdr := http.Header{}
+		for k, v := range endpoint.Headers {
+			hdr.Set(k, v)
+		}
+		opts = append(opts, rpc.WithHeaders(hdr))
+	}
+
+	rpcClient, err := rpc.DialOptions(ctx, endpoint.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial json-rpc endpoint %q: %w", endpoint.URL, err)
+	}
+
+	return &JSONRPCDataSource{client: ethclient.NewClient(rpcClient), proof: gethclient.New(rpcClient)}, nil
+}
+
+func (d *JSONRPCDataSource) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return d.client.CallContract(ctx, msg, blockNumber)
+}
+
+func (d *JSONRPCDataSource) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return d.client.HeaderByNumber(ctx, number)
+}
+
+func (d *JSONRPCDataSource) HeaderByHash(ctx context.Context, hash [32]byte) (*types.Header, error) {
+	return d.client.HeaderByHash(ctx, hash)
+}
+
+func (d *JSONRPCDataSource) ChainID(ctx context.Context) (*big.Int, error) {
+	return d.client.ChainID(ctx)
+}
+
+func (d *JSONRPCDataSource) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	return d.proof.GetProof(ctx, address, storageKeys, blockNumber)
+}
+
+func (d *JSONRPCDataSource) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return d.client.FilterLogs(ctx, q)
+}
+
+func (d *JSONRPCDataSource) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return d.client.SubscribeFilterLogs(ctx, q, ch)
+}
+
+func (d *JSONRPCDataSource) Close() {
+	d.client.Close()
+}
+
+// IPCDataSource talks to a chain over a local IPC socket, which is the
+// lowest-latency option when the performer runs alongside its node.
+type IPCDataSource struct {
+	client *ethclient.Client
+	proof  *gethclient.Client
+}
+
+// NewIPCDataSource dials the given unix socket path.
+func NewIPCDataSource(ctx context.Context, path string) (*IPCDataSource, error) {
+	rpcClient, err := rpc.DialContext(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial ipc endpoint %q: %w", path, err)
+	}
+	return &IPCDataSource{client: ethclient.NewClient(rpcClient), proof: gethclient.New(rpcClient)}, nil
+}
+
+func (d *IPCDataSource) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return d.client.CallContract(ctx, msg, blockNumber)
+}
+
+func (d *IPCDataSource) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return d.client.HeaderByNumber(ctx, number)
+}
+
+func (d *IPCDataSource) HeaderByHash(ctx context.Context, hash [32]byte) (*types.Header, error) {
+	return d.client.HeaderByHash(ctx, hash)
+}
+
+func (d *IPCDataSource) ChainID(ctx context.Context) (*big.Int, error) {
+	return d.client.ChainID(ctx)
+}
+
+func (d *IPCDataSource) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	return d.proof.GetProof(ctx, address, storageKeys, blockNumber)
+}
+
+func (d *IPCDataSource) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return d.client.FilterLogs(ctx, q)
+}
+
+func (d *IPCDataSource) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return d.client.SubscribeFilterLogs(ctx, q, ch)
+}
+
+func (d *IPCDataSource) Close() {
+	d.client.Close()
+}
+
+// InProcessDataSource wraps an already-constructed *ethclient.Client, so
+// callers (tests, or hosts embedding the performer) can inject a client
+// without the worker owning its lifecycle.
+type InProcessDataSource struct {
+	client *ethclient.Client
+	proof  *gethclient.Client
+}
+
+// NewInProcessDataSource wraps an existing client. The caller retains
+// ownership and Close is a no-op. rpcClient may be nil if the caller
+// doesn't need GetProof support.
+func NewInProcessDataSource(client *ethclient.Client, rpcClient *rpc.Client) *InProcessDataSource {
+	ds := &InProcessDataSource{client: client}
+	if rpcClient != nil {
+		ds.proof = gethclient.New(rpcClient)
+	}
+	return ds
+}
+
+func (d *InProcessDataSource) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return d.client.CallContract(ctx, msg, blockNumber)
+}
+
+func (d *InProcessDataSource) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return d.client.HeaderByNumber(ctx, number)
+}
+
+func (d *InProcessDataSource) HeaderByHash(ctx context.Context, hash [32]byte) (*types.Header, error) {
+	return d.client.HeaderByHash(ctx, hash)
+}
+
+func (d *InProcessDataSource) ChainID(ctx context.Context) (*big.Int, error) {
+	return d.client.ChainID(ctx)
+}
+
+func (d *InProcessDataSource) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	if d.proof == nil {
+		return nil, fmt.Errorf("in-process data source was constructed without an rpc.Client, GetProof is unavailable")
+	}
+	return d.proof.GetProof(ctx, address, storageKeys, blockNumber)
+}
+
+func (d *InProcessDataSource) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return d.client.FilterLogs(ctx, q)
+}
+
+func (d *InProcessDataSource) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return d.client.SubscribeFilterLogs(ctx, q, ch)
+}
+
+func (d *InProcessDataSource) Close() {}
+
+// retryingDataSource wraps a DataSource with a bounded retry/backoff
+// policy, so a single transient failure against one endpoint (a dropped
+// connection, a momentary rate limit) doesn't immediately escalate to
+// FallbackDataSource's round-robin failover. SubscribeFilterLogs and
+// Close are forwarded as-is: a live subscription can't be transparently
+// retried once established, and Close has nothing to retry.
+type retryingDataSource struct {
+	DataSource
+	policy RetryPolicy
+}
+
+// withRetry calls fn until it succeeds, ctx is cancelled, or policy's
+// attempt budget is exhausted, backing off exponentially between
+// attempts (capped at MaxDelay).
+func (d *retryingDataSource) withRetry(ctx context.Context, fn func() error) error {
+	attempts := d.policy.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+	delay := d.policy.InitialDelay
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		lastErr = fn()
+		if lastErr == nil {
+			return nil
+		}
+		if attempt == attempts-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if d.policy.MaxDelay > 0 && delay > d.policy.MaxDelay {
+			delay = d.policy.MaxDelay
+		}
+	}
+	return lastErr
+}
+
+func (d *retryingDataSource) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := d.withRetry(ctx, func() error {
+		r, err := d.DataSource.CallContract(ctx, msg, blockNumber)
+		result = r
+		return err
+	})
+	return result, err
+}
+
+func (d *retryingDataSource) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := d.withRetry(ctx, func() error {
+		h, err := d.DataSource.HeaderByNumber(ctx, number)
+		header = h
+		return err
+	})
+	return header, err
+}
+
+func (d *retryingDataSource) HeaderByHash(ctx context.Context, hash [32]byte) (*types.Header, error) {
+	var header *types.Header
+	err := d.withRetry(ctx, func() error {
+		h, err := d.DataSource.HeaderByHash(ctx, hash)
+		header = h
+		return err
+	})
+	return header, err
+}
+
+func (d *retryingDataSource) ChainID(ctx context.Context) (*big.Int, error) {
+	var chainID *big.Int
+	err := d.withRetry(ctx, func() error {
+		id, err := d.DataSource.ChainID(ctx)
+		chainID = id
+		return err
+	})
+	return chainID, err
+}
+
+func (d *retryingDataSource) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	var proof *gethclient.AccountResult
+	err := d.withRetry(ctx, func() error {
+		p, err := d.DataSource.GetProof(ctx, address, storageKeys, blockNumber)
+		proof = p
+		return err
+	})
+	return proof, err
+}
+
+func (d *retryingDataSource) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := d.withRetry(ctx, func() error {
+		l, err := d.DataSource.FilterLogs(ctx, q)
+		logs = l
+		return err
+	})
+	return logs, err
+}
+
+var _ DataSource = (*retryingDataSource)(nil)
+
+// circuitState tracks a single endpoint's health inside FallbackDataSource.
+type circuitState struct {
+	mu             sync.Mutex
+	consecFailures int
+	openUntil      time.Time
+}
+
+func (c *circuitState) isOpen() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Before(c.openUntil)
+}
+
+func (c *circuitState) recordSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consecFailures = 0
+	c.openUntil = time.Time{}
+}
+
+func (c *circuitState) recordFailure(threshold int, cooldown time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consecFailures++
+	if c.consecFailures >= threshold {
+		c.openUntil = time.Now().Add(cooldown)
+	}
+}
+
+// FallbackDataSource round-robins across a set of endpoints and trips a
+// per-endpoint circuit breaker after repeated failures, so a single
+// unhealthy RPC provider doesn't stall every task.
+type FallbackDataSource struct {
+	sources   []DataSource
+	circuits  []*circuitState
+	next      uint64
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+}
+
+// NewFallbackDataSource wires up a round-robin group. threshold is the
+// number of consecutive failures before an endpoint's circuit opens;
+// cooldown is how long it stays open before being retried.
+func NewFallbackDataSource(sources []DataSource, threshold int, cooldown time.Duration) (*FallbackDataSource, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("fallback data source requires at least one endpoint")
+	}
+	circuits := make([]*circuitState, len(sources))
+	for i := range circuits {
+		circuits[i] = &circuitState{}
+	}
+	return &FallbackDataSource{sources: sources, circuits: circuits, threshold: threshold, cooldown: cooldown}, nil
+}
+
+// pick returns the ordered list of source indices to try, starting from
+// the next round-robin position and skipping open circuits.
+func (f *FallbackDataSource) pick() []int {
+	f.mu.Lock()
+	start := f.next
+	f.next++
+	f.mu.Unlock()
+
+	order := make([]int, 0, len(f.sources))
+	n := uint64(len(f.sources))
+	for i := uint64(0); i < n; i++ {
+		idx := int((start + i) % n)
+		if !f.circuits[idx].isOpen() {
+			order = append(order, idx)
+		}
+	}
+	if len(order) == 0 {
+		// Every circuit is open; try them all anyway rather than failing outright.
+		for i := uint64(0); i < n; i++ {
+			order = append(order, int((start+i)%n))
+		}
+	}
+	return order
+}
+
+func (f *FallbackDataSource) try(fn func(DataSource) error) error {
+	var lastErr error
+	for _, idx := range f.pick() {
+		lastErr = fn(f.sources[idx])
+		if lastErr == nil {
+			f.circuits[idx].recordSuccess()
+			return nil
+		}
+		f.circuits[idx].recordFailure(f.threshold, f.cooldown)
+	}
+	return fmt.Errorf("all data source endpoints failed, last error: %w", lastErr)
+}
+
+func (f *FallbackDataSource) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := f.try(func(ds DataSource) error {
+		r, err := ds.CallContract(ctx, msg, blockNumber)
+		result = r
+		return err
+	})
+	return result, err
+}
+
+func (f *FallbackDataSource) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := f.try(func(ds DataSource) error {
+		h, err := ds.HeaderByNumber(ctx, number)
+		header = h
+		return err
+	})
+	return header, err
+}
+
+func (f *FallbackDataSource) HeaderByHash(ctx context.Context, hash [32]byte) (*types.Header, error) {
+	var header *types.Header
+	err := f.try(func(ds DataSource) error {
+		h, err := ds.HeaderByHash(ctx, hash)
+		header = h
+		return err
+	})
+	return header, err
+}
+
+func (f *FallbackDataSource) ChainID(ctx context.Context) (*big.Int, error) {
+	var chainID *big.Int
+	err := f.try(func(ds DataSource) error {
+		id, err := ds.ChainID(ctx)
+		chainID = id
+		return err
+	})
+	return chainID, err
+}
+
+func (f *FallbackDataSource) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	var proof *gethclient.AccountResult
+	err := f.try(func(ds DataSource) error {
+		p, err := ds.GetProof(ctx, address, storageKeys, blockNumber)
+		proof = p
+		return err
+	})
+	return proof, err
+}
+
+func (f *FallbackDataSource) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := f.try(func(ds DataSource) error {
+		l, err := ds.FilterLogs(ctx, q)
+		logs = l
+		return err
+	})
+	return logs, err
+}
+
+// SubscribeFilterLogs tries each endpoint in pick's order until one
+// accepts the subscription. Once established, the subscription stays on
+// that source: a live subscription can't be transparently replayed onto
+// a different source if it later fails.
+func (f *FallbackDataSource) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	order := f.pick()
+	var lastErr error
+	for _, idx := range order {
+		sub, err := f.sources[idx].SubscribeFilterLogs(ctx, q, ch)
+		if err == nil {
+			f.circuits[idx].recordSuccess()
+			return sub, nil
+		}
+		lastErr = err
+		f.circuits[idx].recordFailure(f.threshold, f.cooldown)
+	}
+	return nil, fmt.Errorf("all data source endpoints failed to subscribe, last error: %w", lastErr)
+}
+
+func (f *FallbackDataSource) Close() {
+	for _, s := range f.sources {
+		s.Close()
+	}
+}
+
+// tlsConfigFromSkipVerify is a small helper kept here (rather than in
+// config.go) since it's only ever used to build an endpoint's transport.
+func tlsConfigFromSkipVerify(insecureSkipVerify bool) *tls.Config {
+	if !insecureSkipVerify {
+		return nil
+	}
+	return &tls.Config{InsecureSkipVerify: true}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// archiveProbeSamples is how many historical depths we try, from
+// shallowest to deepest, when classifying a node at startup. A node
+// that can satisfy the deepest sample is treated as archive; one that
+// can't satisfy even the shallowest is classified as unknownStateDepth,
+// under which only the current head is considered safe to read.
+var archiveProbeSamples = []uint64{128, 1024, 100_000}
+
+// unknownStateDepth is probeStateDepth's sentinel for "couldn't even
+// serve state at the shallowest sample depth" — a node classification
+// distinct from (and much more restrictive than) the 0 used for "every
+// sample succeeded, treat as archive". Overloading 0 for both would let
+// a node that can't even look back 128 blocks pass every historical
+// read unchecked, which is the exact case this probe exists to catch.
+const unknownStateDepth = math.MaxUint64
+
+// probeStateDepth estimates how many blocks of historical state the
+// configured data source retains, by attempting a no-op CallContract
+// at increasingly old blocks relative to the chain head. Non-archive
+// nodes prune state after a retention window and return a trie-missing
+// style error once a call falls outside it; archive nodes never do.
+//
+// A returned depth of 0 means the node appears to be a full archive
+// node (every sampled depth succeeded). A non-zero depth is the
+// largest sampled depth that still succeeded, used as a conservative
+// lower bound on how far back reads can safely go. unknownStateDepth
+// means even the shallowest sampled depth failed, so no depth at all
+// is known to be safe.
+func probeStateDepth(ctx context.Context, ds DataSource) (uint64, error) {
+	head, err := ds.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch chain head for archive probe: %w", err)
+	}
+
+	probeMsg := ethereum.CallMsg{To: &common.Address{}}
+
+	var deepestOK uint64
+	for _, depth := range archiveProbeSamples {
+		if head.Number.Cmp(new(big.Int).SetUint64(depth)) <= 0 {
+			// Chain isn't even this deep yet; nothing meaningful to learn.
+			continue
+		}
+		target := new(big.Int).Sub(head.Number, new(big.Int).SetUint64(depth))
+		if _, err := ds.CallContract(ctx, probeMsg, target); err != nil {
+			if deepestOK == 0 {
+				return unknownStateDepth, nil
+			}
+			return deepestOK, nil
+		}
+		deepestOK = depth
+	}
+
+	// Every sample succeeded: treat as archive (no known depth limit).
+	return 0, nil
+}
+
+// resolveCanonicalBlock pins a requested block number to a block hash
+// and guards against the chain reorganizing out from under the read:
+// it fetches the header by number and confirms it by hash up front;
+// the returned verify function re-fetches the header by number after
+// the caller's work and fails if the canonical hash at that height
+// changed in between.
+func resolveCanonicalBlock(ctx context.Context, ds DataSource, blockNumber uint64) (*types.Header, func() error, error) {
+	header, err := ds.HeaderByNumber(ctx, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve header for block %d: %w", blockNumber, err)
+	}
+
+	pinnedHash := header.Hash()
+	if _, err := ds.HeaderByHash(ctx, pinnedHash); err != nil {
+		return nil, nil, fmt.Errorf("failed to confirm pinned block hash %s: %w", pinnedHash, err)
+	}
+
+	verify := func() error {
+		current, err := ds.HeaderByNumber(ctx, new(big.Int).SetUint64(blockNumber))
+		if err != nil {
+			return fmt.Errorf("failed to re-resolve header for block %d: %w", blockNumber, err)
+		}
+		if current.Hash() != pinnedHash {
+			return fmt.Errorf("block %d was reorganized during read: pinned %s, now %s", blockNumber, pinnedHash, current.Hash())
+		}
+		return nil
+	}
+
+	return header, verify, nil
+}
+
+// checkStateDepth rejects reads for blocks older than the node's
+// known state retention window, with a clear error rather than a
+// confusing upstream trie-missing failure.
+func (tw *TaskWorker) checkStateDepth(ctx context.Context, blockNumber uint64) error {
+	if tw.stateDepth == 0 {
+		return nil
+	}
+
+	head, err := tw.dataSource.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch chain head: %w", err)
+	}
+
+	if tw.stateDepth == unknownStateDepth {
+		if blockNumber < head.Number.Uint64() {
+			return fmt.Errorf("this node's state depth could not be classified (it failed even the shallowest archive probe); only the current head (%d) is considered safe, rejecting block %d", head.Number.Uint64(), blockNumber)
+		}
+		return nil
+	}
+
+	if head.Number.Uint64() > tw.stateDepth && blockNumber < head.Number.Uint64()-tw.stateDepth {
+		return fmt.Errorf("block %d is older than this node's retained state depth (%d blocks); use an archive endpoint", blockNumber, tw.stateDepth)
+	}
+	return nil
+}
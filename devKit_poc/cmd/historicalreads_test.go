@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// headerAt builds a minimal header for block number n, distinguishable
+// by a hash derived from n (headers in these tests never need to be
+// cryptographically real, just distinct and stable).
+func headerAt(n uint64) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(n), Extra: []byte(fmt.Sprintf("block-%d", n))}
+}
+
+func TestProbeStateDepthArchiveNode(t *testing.T) {
+	ds := &fakeDataSource{
+		headerByNumber: func(ctx context.Context, number *big.Int) (*types.Header, error) {
+			return headerAt(150_000), nil
+		},
+		callContract: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			return nil, nil // every sampled depth succeeds
+		},
+	}
+
+	depth, err := probeStateDepth(context.Background(), ds)
+	if err != nil {
+		t.Fatalf("probeStateDepth returned unexpected error: %v", err)
+	}
+	if depth != 0 {
+		t.Fatalf("expected depth 0 for an archive node, got %d", depth)
+	}
+}
+
+func TestProbeStateDepthPrunedNode(t *testing.T) {
+	ds := &fakeDataSource{
+		headerByNumber: func(ctx context.Context, number *big.Int) (*types.Header, error) {
+			return headerAt(150_000), nil
+		},
+		callContract: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			// 150_000 - 128 and 150_000 - 1024 are within range; 150_000 - 100_000 is not.
+			if blockNumber.Uint64() < 150_000-1024 {
+				return nil, fmt.Errorf("missing trie node")
+			}
+			return nil, nil
+		},
+	}
+
+	depth, err := probeStateDepth(context.Background(), ds)
+	if err != nil {
+		t.Fatalf("probeStateDepth returned unexpected error: %v", err)
+	}
+	if depth != 1024 {
+		t.Fatalf("expected depth 1024 (deepest successful sample), got %d", depth)
+	}
+}
+
+func TestProbeStateDepthUnclassifiableNode(t *testing.T) {
+	ds := &fakeDataSource{
+		headerByNumber: func(ctx context.Context, number *big.Int) (*types.Header, error) {
+			return headerAt(150_000), nil
+		},
+		callContract: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			return nil, fmt.Errorf("missing trie node") // fails even the shallowest sample
+		},
+	}
+
+	depth, err := probeStateDepth(context.Background(), ds)
+	if err != nil {
+		t.Fatalf("probeStateDepth returned unexpected error: %v", err)
+	}
+	if depth != unknownStateDepth {
+		t.Fatalf("expected unknownStateDepth sentinel, got %d", depth)
+	}
+	if depth == 0 {
+		t.Fatalf("unknownStateDepth must not collide with the archive-node value 0")
+	}
+}
+
+func TestResolveCanonicalBlockSuccess(t *testing.T) {
+	header := headerAt(100)
+	ds := &fakeDataSource{
+		headerByNumber: func(ctx context.Context, number *big.Int) (*types.Header, error) {
+			return header, nil
+		},
+		headerByHash: func(ctx context.Context, hash [32]byte) (*types.Header, error) {
+			if hash != header.Hash() {
+				return nil, fmt.Errorf("unexpected hash lookup")
+			}
+			return header, nil
+		},
+	}
+
+	got, verify, err := resolveCanonicalBlock(context.Background(), ds, 100)
+	if err != nil {
+		t.Fatalf("resolveCanonicalBlock returned unexpected error: %v", err)
+	}
+	if got.Hash() != header.Hash() {
+		t.Fatalf("expected resolved header to match")
+	}
+	if err := verify(); err != nil {
+		t.Fatalf("verify returned unexpected error for an unchanged chain: %v", err)
+	}
+}
+
+func TestResolveCanonicalBlockDetectsReorg(t *testing.T) {
+	original := headerAt(100)
+	reorged := headerAt(100)
+	reorged.Extra = []byte("reorged")
+
+	calls := 0
+	ds := &fakeDataSource{
+		headerByNumber: func(ctx context.Context, number *big.Int) (*types.Header, error) {
+			calls++
+			if calls == 1 {
+				return original, nil
+			}
+			return reorged, nil
+		},
+		headerByHash: func(ctx context.Context, hash [32]byte) (*types.Header, error) {
+			return original, nil
+		},
+	}
+
+	_, verify, err := resolveCanonicalBlock(context.Background(), ds, 100)
+	if err != nil {
+		t.Fatalf("resolveCanonicalBlock returned unexpected error: %v", err)
+	}
+	if err := verify(); err == nil {
+		t.Fatalf("expected verify to detect the reorg")
+	}
+}
+
+func TestCheckStateDepthRejectsBlockOlderThanRetention(t *testing.T) {
+	ds := &fakeDataSource{
+		headerByNumber: func(ctx context.Context, number *big.Int) (*types.Header, error) {
+			return headerAt(1_000_000), nil
+		},
+	}
+	tw := &TaskWorker{dataSource: ds, stateDepth: 1024}
+
+	if err := tw.checkStateDepth(context.Background(), 1_000_000-2048); err == nil {
+		t.Fatalf("expected an error for a block older than the retained depth")
+	}
+	if err := tw.checkStateDepth(context.Background(), 1_000_000-100); err != nil {
+		t.Fatalf("expected a recent block to pass, got: %v", err)
+	}
+}
+
+func TestCheckStateDepthUnknownRejectsAnyNonHeadBlock(t *testing.T) {
+	ds := &fakeDataSource{
+		headerByNumber: func(ctx context.Context, number *big.Int) (*types.Header, error) {
+			return headerAt(1_000_000), nil
+		},
+	}
+	tw := &TaskWorker{dataSource: ds, stateDepth: unknownStateDepth}
+
+	if err := tw.checkStateDepth(context.Background(), 999_999); err == nil {
+		t.Fatalf("expected every non-head block to be rejected when the node is unclassifiable")
+	}
+	if err := tw.checkStateDepth(context.Background(), 1_000_000); err != nil {
+		t.Fatalf("expected the current head to be allowed, got: %v", err)
+	}
+}
+
+func TestCheckStateDepthZeroAllowsAnyBlock(t *testing.T) {
+	tw := &TaskWorker{stateDepth: 0}
+	if err := tw.checkStateDepth(context.Background(), 1); err != nil {
+		t.Fatalf("expected an archive-classified node to allow any block, got: %v", err)
+	}
+}
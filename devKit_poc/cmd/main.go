@@ -4,23 +4,34 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/big"
-	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/Layr-Labs/hourglass-monorepo/ponos/pkg/performer/server"
 	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
 // TaskWorker implements counter reading for oracle-based validation
 type TaskWorker struct {
-	logger    *zap.Logger
-	ethClient *ethclient.Client
+	logger     *zap.Logger
+	dataSource DataSource
+	// stateDepth is the number of blocks of historical state the data
+	// source is known to retain, as classified by probeStateDepth at
+	// startup. Zero means the node behaved like a full archive node.
+	stateDepth uint64
+	// chainID is the chain the configured dataSource is actually
+	// connected to, fetched once at startup. Calls that declare a
+	// ChainID are checked against it so a payload can't silently read
+	// the wrong chain.
+	chainID uint64
+	// cache and group together make repeated (address, block) reads
+	// cheap: cache serves unexpired hits, group coalesces concurrent
+	// identical misses into a single upstream call.
+	cache   *resultCache
+	group   singleflight.Group
+	metrics *Metrics
 }
 
 // CounterTask represents the task payload structure
@@ -40,50 +51,144 @@ const CounterABI = `[
 	}
 ]`
 
-func NewTaskWorker(logger *zap.Logger) *TaskWorker {
-	// Connect to local Ethereum client (anvil)
-	client, err := ethclient.Dial("http://localhost:8545")
+// NewTaskWorker builds the DataSource described by cfg (one endpoint
+// becomes a single JSONRPCDataSource/IPCDataSource, several become a
+// FallbackDataSource) and verifies its chain ID against cfg.ExpectedChainID
+// before returning, so a misconfigured RPC fails fast at startup rather
+// than on the first HandleTask call.
+func NewTaskWorker(logger *zap.Logger, cfg WorkerConfig) (*TaskWorker, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	metrics, registry := NewMetrics()
+	serveMetrics(context.Background(), cfg.MetricsPort, registry, logger)
+
+	dataSource, err := buildDataSource(ctx, cfg, metrics)
 	if err != nil {
-		logger.Sugar().Fatalw("Failed to connect to Ethereum client", "error", err)
+		return nil, fmt.Errorf("failed to build data source: %w", err)
 	}
 
-	return &TaskWorker{
-		logger:    logger,
-		ethClient: client,
+	chainID, err := dataSource.ChainID(ctx)
+	if err != nil {
+		dataSource.Close()
+		return nil, fmt.Errorf("failed to determine connected chain id: %w", err)
+	}
+	if cfg.ExpectedChainID != 0 && chainID.Uint64() != cfg.ExpectedChainID {
+		dataSource.Close()
+		return nil, fmt.Errorf("chain id mismatch: expected %d, got %d", cfg.ExpectedChainID, chainID.Uint64())
 	}
-}
 
-func (tw *TaskWorker) ValidateTask(t *performerV1.TaskRequest) error {
-	tw.logger.Sugar().Infow("Validating counter reading task",
-		zap.Any("task", t),
-	)
+	stateDepth, err := probeStateDepth(ctx, dataSource)
+	if err != nil {
+		dataSource.Close()
+		return nil, fmt.Errorf("failed to classify data source archive depth: %w", err)
+	}
 
-	// Parse task payload to validate it's a counter reading task
-	var task CounterTask
-	if err := json.Unmarshal(t.Payload, &task); err != nil {
-		return fmt.Errorf("invalid task payload: %w", err)
+	return &TaskWorker{
+		logger:     logger,
+		dataSource: dataSource,
+		stateDepth: stateDepth,
+		chainID:    chainID.Uint64(),
+		cache:      newResultCache(cfg.CacheSize, cfg.CacheTTL),
+		metrics:    metrics,
+	}, nil
+}
+
+// buildDataSource constructs the appropriate DataSource implementation
+// for the configured endpoints, instrumenting each with per-endpoint
+// metrics and wrapping that in cfg.Retry's retry/backoff policy — in
+// that order, so UpstreamLatency/UpstreamErrors measure each individual
+// attempt rather than the whole retry loop including backoff sleeps —
+// then wrapping multiple endpoints in a FallbackDataSource.
+func buildDataSource(ctx context.Context, cfg WorkerConfig, metrics *Metrics) (DataSource, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, fmt.Errorf("worker config must list at least one endpoint")
 	}
 
-	// Basic validation
-	if task.CounterAddress == "" {
-		return fmt.Errorf("counter address is required")
+	sources := make([]DataSource, 0, len(cfg.Endpoints))
+	for _, endpoint := range cfg.Endpoints {
+		var (
+			source DataSource
+			err    error
+		)
+		if endpoint.IPCPath != "" {
+			source, err = NewIPCDataSource(ctx, endpoint.IPCPath)
+		} else {
+			source, err = NewJSONRPCDataSource(ctx, endpoint)
+		}
+		if err != nil {
+			for _, s := range sources {
+				s.Close()
+			}
+			return nil, err
+		}
+		instrumented := &instrumentedDataSource{DataSource: source, metrics: metrics, label: endpointLabel(endpoint)}
+		sources = append(sources, &retryingDataSource{DataSource: instrumented, policy: cfg.Retry})
 	}
 
-	if task.BlockNumber == 0 {
-		return fmt.Errorf("block number is required")
+	if len(sources) == 1 {
+		return sources[0], nil
 	}
 
-	// Validate counter address format
-	if !common.IsHexAddress(task.CounterAddress) {
-		return fmt.Errorf("invalid counter address format")
+	return NewFallbackDataSource(sources, cfg.CircuitFailureThreshold, cfg.CircuitCooldown)
+}
+
+// endpointLabel picks the metrics label for an endpoint: its
+// configured Name, or else its URL/IPCPath.
+func endpointLabel(endpoint EndpointConfig) string {
+	if endpoint.Name != "" {
+		return endpoint.Name
+	}
+	if endpoint.IPCPath != "" {
+		return endpoint.IPCPath
 	}
+	return endpoint.URL
+}
 
-	tw.logger.Sugar().Infow("Task validation successful",
-		"counterAddress", task.CounterAddress,
-		"blockNumber", task.BlockNumber,
+func (tw *TaskWorker) ValidateTask(t *performerV1.TaskRequest) error {
+	tw.logger.Sugar().Infow("Validating counter reading task",
+		zap.Any("task", t),
 	)
 
-	return nil
+	switch detectTaskType(t.Payload) {
+	case taskTypeWatch:
+		var task CounterWatchTask
+		if err := json.Unmarshal(t.Payload, &task); err != nil {
+			return fmt.Errorf("invalid watch task payload: %w", err)
+		}
+		if !common.IsHexAddress(task.Address) {
+			return fmt.Errorf("invalid contract address format")
+		}
+		if task.EventSignature == "" {
+			return fmt.Errorf("eventSignature is required")
+		}
+		if len(task.EventABI) == 0 {
+			return fmt.Errorf("eventAbi is required")
+		}
+		tw.logger.Sugar().Infow("Watch task validation successful", "address", task.Address)
+		return nil
+
+	default:
+		task, err := asMultiCallTask(t.Payload)
+		if err != nil {
+			return err
+		}
+
+		for i, call := range task.Calls {
+			if call.Address == "" {
+				return fmt.Errorf("call %d: contract address is required", i)
+			}
+			if !common.IsHexAddress(call.Address) {
+				return fmt.Errorf("call %d: invalid contract address format", i)
+			}
+			if len(call.ABI) == 0 && call.Function == "" {
+				return fmt.Errorf("call %d: must specify either an ABI or a function name", i)
+			}
+		}
+
+		tw.logger.Sugar().Infow("Task validation successful", "calls", len(task.Calls))
+		return nil
+	}
 }
 
 func (tw *TaskWorker) HandleTask(t *performerV1.TaskRequest) (*performerV1.TaskResponse, error) {
@@ -91,35 +196,31 @@ func (tw *TaskWorker) HandleTask(t *performerV1.TaskRequest) (*performerV1.TaskR
 		zap.Any("task", t),
 	)
 
-	// Parse task payload
-	var task CounterTask
-	if err := json.Unmarshal(t.Payload, &task); err != nil {
+	if detectTaskType(t.Payload) == taskTypeWatch {
+		return tw.handleWatchTask(t)
+	}
+
+	task, err := asMultiCallTask(t.Payload)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse task payload: %w", err)
 	}
 
-	// Read counter value at specified block
-	counterValue, err := tw.readCounterAtBlock(task.CounterAddress, task.BlockNumber)
+	results, err := tw.executeMultiCall(context.Background(), task)
 	if err != nil {
-		tw.logger.Sugar().Errorw("Failed to read counter value", "error", err)
+		tw.logger.Sugar().Errorw("Failed to execute multi-call task", "error", err)
 		return &performerV1.TaskResponse{
 			TaskId: t.TaskId,
 			Result: nil,
 		}, err
 	}
 
-	// Prepare result - encode as the smart contract expects (uint256, uint256)
-	result, err := abi.Arguments{{Type: abi.Type{T: abi.UintTy, Size: 256}}, {Type: abi.Type{T: abi.UintTy, Size: 256}}}.Pack(
-		counterValue,
-		big.NewInt(int64(task.BlockNumber)),
-	)
+	result, err := packMultiCallResults(task, results)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode result: %w", err)
 	}
 
-	tw.logger.Sugar().Infow("Successfully handled counter reading task",
-		"counterAddress", task.CounterAddress,
-		"counterValue", counterValue,
-		"blockNumber", task.BlockNumber,
+	tw.logger.Sugar().Infow("Successfully handled multi-call task",
+		"calls", len(task.Calls),
 	)
 
 	return &performerV1.TaskResponse{
@@ -128,54 +229,53 @@ func (tw *TaskWorker) HandleTask(t *performerV1.TaskRequest) (*performerV1.TaskR
 	}, nil
 }
 
-// readCounterAtBlock reads the counter value at a specific block
-func (tw *TaskWorker) readCounterAtBlock(counterAddr string, blockNumber uint64) (*big.Int, error) {
-	// Parse counter ABI
-	parsedABI, err := abi.JSON(strings.NewReader(CounterABI))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse counter ABI: %w", err)
-	}
-
-	// Prepare call data for getCurrentValue()
-	callData, err := parsedABI.Pack("getCurrentValue")
-	if err != nil {
-		return nil, fmt.Errorf("failed to pack call data: %w", err)
-	}
-
-	// Make the call at specific block
-	msg := ethereum.CallMsg{
-		To:   &common.Address{},
-		Data: callData,
+// handleWatchTask decodes and executes a CounterWatchTask, returning
+// matching events ABI-encoded as parallel arrays (see packWatchResults).
+func (tw *TaskWorker) handleWatchTask(t *performerV1.TaskRequest) (*performerV1.TaskResponse, error) {
+	var task CounterWatchTask
+	if err := json.Unmarshal(t.Payload, &task); err != nil {
+		return nil, fmt.Errorf("failed to parse watch task payload: %w", err)
 	}
-	copy(msg.To[:], common.HexToAddress(counterAddr).Bytes())
 
-	result, err := tw.ethClient.CallContract(context.Background(), msg, big.NewInt(int64(blockNumber)))
+	logs, event, err := tw.executeWatchTask(context.Background(), task)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call counter contract: %w", err)
+		tw.logger.Sugar().Errorw("Failed to execute watch task", "error", err)
+		return &performerV1.TaskResponse{
+			TaskId: t.TaskId,
+			Result: nil,
+		}, err
 	}
 
-	// Unpack the result
-	var counterValue *big.Int
-	err = parsedABI.UnpackIntoInterface(&counterValue, "getCurrentValue", result)
+	result, err := packWatchResults(event, logs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unpack result: %w", err)
+		return nil, fmt.Errorf("failed to encode watch result: %w", err)
 	}
 
-	tw.logger.Sugar().Infow("Successfully read counter value",
-		"counterAddress", counterAddr,
-		"blockNumber", blockNumber,
-		"value", counterValue,
+	tw.logger.Sugar().Infow("Successfully handled watch task",
+		"address", task.Address,
+		"matches", len(logs),
 	)
 
-	return counterValue, nil
+	return &performerV1.TaskResponse{
+		TaskId: t.TaskId,
+		Result: result,
+	}, nil
 }
 
 func main() {
 	ctx := context.Background()
 	l, _ := zap.NewProduction()
 
+	cfg, err := LoadWorkerConfig()
+	if err != nil {
+		l.Sugar().Fatalw("Failed to load worker config", "error", err)
+	}
+
 	// Create task worker
-	w := NewTaskWorker(l)
+	w, err := NewTaskWorker(l, cfg)
+	if err != nil {
+		l.Sugar().Fatalw("Failed to create task worker", "error", err)
+	}
 
 	// Create and start the Hourglass performer server
 	pp, err := server.NewPonosPerformerWithRpcServer(&server.PonosPerformerConfig{
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxConcurrentCalls bounds how many contract reads a single task can
+// run at once, so a payload listing many calls can't exhaust upstream
+// RPC connections.
+const maxConcurrentCalls = 8
+
+// CallSpec describes a single contract read within a MultiCallTask.
+// Function is always required: with ABI set, it names the method in that
+// caller-supplied ABI fragment; without ABI, it is looked up in the
+// built-in CounterABI, a shorthand for the legacy getCurrentValue()-shaped
+// reads.
+type CallSpec struct {
+	// ChainID, when set, is checked against the chain the worker's
+	// DataSource is actually connected to; a mismatch fails the call
+	// rather than silently reading the wrong chain. This worker is
+	// wired to a single DataSource, so it cannot route calls to other
+	// chains itself, only reject calls that don't belong to its chain.
+	ChainID     uint64          `json:"chainId"`
+	Address     string          `json:"address"`
+	ABI         json.RawMessage `json:"abi,omitempty"`
+	Function    string          `json:"function,omitempty"`
+	Args        []interface{}   `json:"args,omitempty"`
+	Outputs     []string        `json:"outputs,omitempty"`
+	BlockNumber *uint64         `json:"blockNumber,omitempty"`
+	BlockHash   string          `json:"blockHash,omitempty"`
+	// ProofMode requests a verifiable eth_getProof-backed result
+	// instead of a bare value, at the cost of an extra RPC round trip.
+	ProofMode bool `json:"proofMode,omitempty"`
+	// StorageSlot is the storage slot (hex) backing the value read by
+	// Function, required when ProofMode is set.
+	StorageSlot string `json:"storageSlot,omitempty"`
+}
+
+// MultiCallTask is the generic oracle-read payload: one or more contract
+// calls, decoded against caller-supplied ABIs rather than the single
+// hardcoded CounterABI. Every call is read through the worker's one
+// connected chain; a call declaring a different ChainID is rejected, not
+// routed elsewhere.
+type MultiCallTask struct {
+	Calls []CallSpec `json:"calls"`
+}
+
+// callResult is the decoded outcome of a single CallSpec, kept in the
+// same order as the input calls so results can be packed deterministically.
+type callResult struct {
+	index     int
+	values    []interface{}
+	blockHash common.Hash
+	proof     *StateProof
+	err       error
+}
+
+// abiCache memoizes parsed ABIs by a hash of their raw JSON, since the
+// same ABI is typically reused across many calls in a task.
+var abiCache sync.Map // map[string]abi.ABI
+
+func parseCachedABI(raw json.RawMessage) (abi.ABI, error) {
+	sum := sha256.Sum256(raw)
+	key := hex.EncodeToString(sum[:])
+
+	if cached, ok := abiCache.Load(key); ok {
+		return cached.(abi.ABI), nil
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(string(raw)))
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+	abiCache.Store(key, parsed)
+	return parsed, nil
+}
+
+// asMultiCallTask normalizes a task payload into a MultiCallTask. The
+// legacy {counterAddress, blockNumber} shape is translated into a single
+// getCurrentValue call so existing callers keep working unchanged.
+func asMultiCallTask(payload []byte) (MultiCallTask, error) {
+	var legacy CounterTask
+	if err := json.Unmarshal(payload, &legacy); err == nil && legacy.CounterAddress != "" {
+		blockNumber := legacy.BlockNumber
+		return MultiCallTask{
+			Calls: []CallSpec{{
+				Address:     legacy.CounterAddress,
+				Function:    "getCurrentValue",
+				Outputs:     []string{"uint256"},
+				BlockNumber: &blockNumber,
+			}},
+		}, nil
+	}
+
+	var task MultiCallTask
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&task); err != nil {
+		return MultiCallTask{}, fmt.Errorf("invalid task payload: %w", err)
+	}
+	if len(task.Calls) == 0 {
+		return MultiCallTask{}, fmt.Errorf("task must contain at least one call")
+	}
+	return task, nil
+}
+
+// executeMultiCall runs every call in the task concurrently (bounded by
+// maxConcurrentCalls), decodes each against its ABI, and returns
+// results in input order.
+func (tw *TaskWorker) executeMultiCall(ctx context.Context, task MultiCallTask) ([]callResult, error) {
+	results := make([]callResult, len(task.Calls))
+	sem := make(chan struct{}, maxConcurrentCalls)
+	var wg sync.WaitGroup
+
+	for i, call := range task.Calls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, call CallSpec) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = tw.executeCallCached(ctx, i, call)
+		}(i, call)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if r.err != nil {
+			return nil, fmt.Errorf("call %d failed: %w", r.index, r.err)
+		}
+	}
+	return results, nil
+}
+
+// executeCallCached is the entry point executeMultiCall uses for each
+// call: it serves unexpired cache hits directly, and otherwise routes
+// the miss through singleflight so concurrent identical reads (same
+// chain, address, selector, args, and block) share one upstream call.
+func (tw *TaskWorker) executeCallCached(ctx context.Context, index int, call CallSpec) callResult {
+	key := cacheKeyFor(call)
+
+	if cached, ok := tw.cache.get(key); ok {
+		tw.metrics.CacheHits.Inc()
+		cached.index = index
+		return cached
+	}
+	tw.metrics.CacheMisses.Inc()
+
+	v, err, shared := tw.group.Do(key, func() (interface{}, error) {
+		result := tw.executeCall(ctx, index, call)
+		if result.err != nil {
+			return nil, result.err
+		}
+		return result, nil
+	})
+	if shared {
+		tw.metrics.Coalesced.Inc()
+	}
+	if err != nil {
+		return callResult{index: index, err: err}
+	}
+
+	result := v.(callResult)
+	result.index = index
+	tw.cache.put(key, result)
+	return result
+}
+
+// cacheKeyFor builds the resultCache key for a call: chain, address,
+// function/ABI-hash, args, proof settings, and the requested block (the
+// caller-supplied BlockHash string if set, else the BlockNumber), so two
+// calls only collide when every one of those inputs matches. The key is
+// built before the block is resolved, so it does not contain the
+// resolved canonical block hash.
+func cacheKeyFor(call CallSpec) string {
+	var abiKey string
+	if len(call.ABI) > 0 {
+		sum := sha256.Sum256(call.ABI)
+		abiKey = hex.EncodeToString(sum[:])
+	}
+
+	argsJSON, _ := json.Marshal(call.Args)
+
+	block := call.BlockHash
+	if block == "" && call.BlockNumber != nil {
+		block = fmt.Sprintf("#%d", *call.BlockNumber)
+	}
+
+	return fmt.Sprintf("%d|%s|%s|%s|%s|%s|%t|%s", call.ChainID, call.Address, abiKey, call.Function, argsJSON, block, call.ProofMode, call.StorageSlot)
+}
+
+// executeCall resolves the ABI/function for a single CallSpec, pins the
+// requested block to a canonical hash (rejecting blocks outside the
+// node's retained state depth), packs the call data, performs the
+// read, and unpacks the outputs. The block is re-verified after the
+// call so a reorg during execution is caught rather than silently
+// returning a result for the wrong chain history.
+func (tw *TaskWorker) executeCall(ctx context.Context, index int, call CallSpec) callResult {
+	if call.ChainID != 0 && call.ChainID != tw.chainID {
+		return callResult{index: index, err: fmt.Errorf("call targets chain %d but this worker is connected to chain %d", call.ChainID, tw.chainID)}
+	}
+
+	parsedABI, functionName, err := resolveCallABI(call)
+	if err != nil {
+		return callResult{index: index, err: err}
+	}
+
+	method, ok := parsedABI.Methods[functionName]
+	if !ok {
+		return callResult{index: index, err: fmt.Errorf("function %q not found in ABI", functionName)}
+	}
+	coercedArgs, err := coerceCallArgs(method.Inputs, call.Args)
+	if err != nil {
+		return callResult{index: index, err: fmt.Errorf("invalid arguments for %q: %w", functionName, err)}
+	}
+
+	callData, err := parsedABI.Pack(functionName, coercedArgs...)
+	if err != nil {
+		return callResult{index: index, err: fmt.Errorf("failed to pack call data for %q: %w", functionName, err)}
+	}
+
+	if !common.IsHexAddress(call.Address) {
+		return callResult{index: index, err: fmt.Errorf("invalid contract address %q", call.Address)}
+	}
+
+	var (
+		blockNumber  *big.Int
+		blockHash    common.Hash
+		verify       func() error
+		pinnedHeader *types.Header
+	)
+	if call.BlockNumber != nil {
+		if err := tw.checkStateDepth(ctx, *call.BlockNumber); err != nil {
+			return callResult{index: index, err: err}
+		}
+		header, v, err := resolveCanonicalBlock(ctx, tw.dataSource, *call.BlockNumber)
+		if err != nil {
+			return callResult{index: index, err: err}
+		}
+		blockNumber = header.Number
+		blockHash = header.Hash()
+		verify = v
+		pinnedHeader = header
+	}
+
+	addr := common.HexToAddress(call.Address)
+	msg := ethereum.CallMsg{To: &addr, Data: callData}
+
+	raw, err := tw.dataSource.CallContract(ctx, msg, blockNumber)
+	if err != nil {
+		return callResult{index: index, err: fmt.Errorf("failed to call contract %s: %w", call.Address, err)}
+	}
+
+	if verify != nil {
+		if err := verify(); err != nil {
+			return callResult{index: index, err: err}
+		}
+	}
+
+	values, err := parsedABI.Unpack(functionName, raw)
+	if err != nil {
+		return callResult{index: index, err: fmt.Errorf("failed to unpack result of %q: %w", functionName, err)}
+	}
+
+	var proof *StateProof
+	if call.ProofMode {
+		if call.BlockNumber == nil {
+			return callResult{index: index, err: fmt.Errorf("proofMode requires a pinned blockNumber")}
+		}
+		if call.StorageSlot == "" {
+			return callResult{index: index, err: fmt.Errorf("proofMode requires a storageSlot")}
+		}
+		proof, err = fetchStateProof(ctx, tw.dataSource, pinnedHeader, addr, common.HexToHash(call.StorageSlot))
+		if err != nil {
+			return callResult{index: index, err: err}
+		}
+		proofValue, err := verifyStateProof(proof.StateRoot, addr, common.HexToHash(call.StorageSlot), proof)
+		if err != nil {
+			return callResult{index: index, err: fmt.Errorf("self-check of generated proof failed: %w", err)}
+		}
+		if len(values) != 1 {
+			return callResult{index: index, err: fmt.Errorf("proofMode requires exactly one decoded output, got %d", len(values))}
+		}
+		decodedValue, ok := values[0].(*big.Int)
+		if !ok {
+			return callResult{index: index, err: fmt.Errorf("proofMode requires a uint256 output, got %T", values[0])}
+		}
+		if proofValue.Cmp(decodedValue) != 0 {
+			return callResult{index: index, err: fmt.Errorf("proof value %s for storage slot %s does not match decoded call value %s", proofValue, call.StorageSlot, decodedValue)}
+		}
+	}
+
+	return callResult{index: index, values: values, blockHash: blockHash, proof: proof}
+}
+
+// resolveCallABI returns the ABI and function name to use for a call:
+// a caller-supplied ABI fragment, or the built-in CounterABI when the
+// caller only gave a bare function name (the common "getCurrentValue"
+// shorthand).
+func resolveCallABI(call CallSpec) (abi.ABI, string, error) {
+	if len(call.ABI) > 0 {
+		parsed, err := parseCachedABI(call.ABI)
+		if err != nil {
+			return abi.ABI{}, "", err
+		}
+		if call.Function == "" {
+			return abi.ABI{}, "", fmt.Errorf("function name is required when ABI is supplied")
+		}
+		return parsed, call.Function, nil
+	}
+
+	if call.Function == "" {
+		return abi.ABI{}, "", fmt.Errorf("call must specify either an ABI or a function name")
+	}
+
+	parsed, err := parseCachedABI(json.RawMessage(CounterABI))
+	if err != nil {
+		return abi.ABI{}, "", err
+	}
+	return parsed, call.Function, nil
+}
+
+// packMultiCallResults ABI-encodes the decoded call results as a tuple
+// matching each call's requested Outputs types, defaulting to uint256
+// when Outputs is omitted (the legacy getCurrentValue shape). Calls
+// that pinned a block number additionally get that block number (as a
+// uint256, keeping the legacy (value, blockNumber) prefix) and the
+// resolved block hash (as a bytes32) appended, and calls with ProofMode
+// set further append
+// (stateRoot, accountProof, storageProof) so consumers can verify the
+// result against the block's state root without trusting this RPC.
+func packMultiCallResults(task MultiCallTask, results []callResult) ([]byte, error) {
+	var args abi.Arguments
+	var packed []interface{}
+
+	for i, call := range task.Calls {
+		decoded := results[i].values
+		outputs := call.Outputs
+		if len(outputs) == 0 {
+			outputs = []string{"uint256"}
+		}
+		if len(outputs) != len(decoded) {
+			return nil, fmt.Errorf("call %d: expected %d outputs, decoded %d", i, len(outputs), len(decoded))
+		}
+		for j, typeName := range outputs {
+			argType, err := abi.NewType(typeName, "", nil)
+			if err != nil {
+				return nil, fmt.Errorf("call %d: invalid output type %q: %w", i, typeName, err)
+			}
+			args = append(args, abi.Argument{Type: argType})
+			packed = append(packed, decoded[j])
+		}
+
+		if call.BlockNumber != nil {
+			uint256Type, err := abi.NewType("uint256", "", nil)
+			if err != nil {
+				return nil, fmt.Errorf("call %d: failed to build uint256 type: %w", i, err)
+			}
+			bytes32Type, err := abi.NewType("bytes32", "", nil)
+			if err != nil {
+				return nil, fmt.Errorf("call %d: failed to build bytes32 type: %w", i, err)
+			}
+			args = append(args, abi.Argument{Type: uint256Type}, abi.Argument{Type: bytes32Type})
+			packed = append(packed, new(big.Int).SetUint64(*call.BlockNumber), results[i].blockHash)
+		}
+
+		if call.ProofMode {
+			bytes32Type, err := abi.NewType("bytes32", "", nil)
+			if err != nil {
+				return nil, fmt.Errorf("call %d: failed to build bytes32 type: %w", i, err)
+			}
+			bytesType, err := abi.NewType("bytes", "", nil)
+			if err != nil {
+				return nil, fmt.Errorf("call %d: failed to build bytes type: %w", i, err)
+			}
+			proof := results[i].proof
+			args = append(args,
+				abi.Argument{Type: bytes32Type},
+				abi.Argument{Type: bytesType},
+				abi.Argument{Type: bytesType},
+			)
+			packed = append(packed, proof.StateRoot, proof.AccountProof, proof.StorageProof)
+		}
+	}
+
+	return args.Pack(packed...)
+}
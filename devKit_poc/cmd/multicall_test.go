@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+const scaleABI = `[
+	{
+		"inputs": [{"internalType": "uint8", "name": "factor", "type": "uint8"}],
+		"name": "scale",
+		"outputs": [{"internalType": "uint32", "name": "", "type": "uint32"}],
+		"stateMutability": "view",
+		"type": "function"
+	}
+]`
+
+// TestExecuteCallNarrowIntegerArgsAndOutputs is the regression test for
+// the bug where coerceCallArgs always produced a *big.Int for integer
+// arguments: go-ethereum's ABI packer rejects a *big.Int for any integer
+// type narrower than 256 bits (uint8, uint32, ...), which Pack would
+// have caught before this call ever reached CallContract. It exercises
+// executeCall end-to-end against a fake contract that returns its uint8
+// input doubled, as a uint32.
+func TestExecuteCallNarrowIntegerArgsAndOutputs(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(scaleABI))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	method := parsed.Methods["scale"]
+
+	ds := &fakeDataSource{
+		callContract: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			args, err := method.Inputs.Unpack(msg.Data[4:])
+			if err != nil {
+				t.Fatalf("failed to unpack call data: %v", err)
+			}
+			factor, ok := args[0].(uint8)
+			if !ok {
+				t.Fatalf("expected uint8 arg, got %T", args[0])
+			}
+			return method.Outputs.Pack(uint32(factor) * 2)
+		},
+	}
+
+	metrics, _ := NewMetrics()
+	tw := &TaskWorker{
+		dataSource: ds,
+		cache:      newResultCache(0, 0),
+		metrics:    metrics,
+	}
+
+	call := CallSpec{
+		Address:  "0x0000000000000000000000000000000000000001",
+		ABI:      json.RawMessage(scaleABI),
+		Function: "scale",
+		Args:     []interface{}{json.Number("7")},
+	}
+
+	result := tw.executeCallCached(context.Background(), 0, call)
+	if result.err != nil {
+		t.Fatalf("executeCallCached returned unexpected error: %v", result.err)
+	}
+	if len(result.values) != 1 {
+		t.Fatalf("expected 1 decoded value, got %d", len(result.values))
+	}
+	got, ok := result.values[0].(uint32)
+	if !ok {
+		t.Fatalf("expected uint32 result, got %T", result.values[0])
+	}
+	if got != 14 {
+		t.Fatalf("expected 14, got %d", got)
+	}
+}
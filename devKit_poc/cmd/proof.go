@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// StateProof carries everything a validator or aggregator needs to
+// independently check a read against a block's state root, without
+// trusting the performer's RPC endpoint.
+type StateProof struct {
+	StateRoot    common.Hash
+	AccountProof []byte // RLP-encoded list of trie nodes
+	StorageProof []byte // RLP-encoded list of trie nodes
+}
+
+// fetchStateProof calls eth_getProof for the account and storage slot
+// backing a counter-style read at the given pinned block, and RLP-packs
+// the account/storage proofs so they travel as single byte blobs.
+func fetchStateProof(ctx context.Context, ds DataSource, header *types.Header, address common.Address, storageSlot common.Hash) (*StateProof, error) {
+	proof, err := ds.GetProof(ctx, address, []string{storageSlot.Hex()}, header.Number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch eth_getProof for %s: %w", address, err)
+	}
+	if len(proof.StorageProof) != 1 {
+		return nil, fmt.Errorf("expected exactly one storage proof for slot %s, got %d", storageSlot, len(proof.StorageProof))
+	}
+
+	accountProofBytes, err := encodeProofNodes(proof.AccountProof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode account proof: %w", err)
+	}
+	storageProofBytes, err := encodeProofNodes(proof.StorageProof[0].Proof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode storage proof: %w", err)
+	}
+
+	return &StateProof{
+		StateRoot:    header.Root,
+		AccountProof: accountProofBytes,
+		StorageProof: storageProofBytes,
+	}, nil
+}
+
+// encodeProofNodes decodes the hex-encoded trie nodes returned by
+// eth_getProof and RLP-encodes them as a single list, so the proof
+// travels through the task response as one byte blob.
+func encodeProofNodes(hexNodes []string) ([]byte, error) {
+	nodes := make([][]byte, len(hexNodes))
+	for i, n := range hexNodes {
+		decoded, err := hexutil.Decode(n)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof node %d: %w", i, err)
+		}
+		nodes[i] = decoded
+	}
+	return rlp.EncodeToBytes(nodes)
+}
+
+// verifyStateProof replays an encoded account+storage proof against the
+// expected state root and returns the storage value it proves, so
+// executeCall can self-check a proof-backed result before HandleTask
+// emits it rather than trusting the RPC endpoint that produced it.
+func verifyStateProof(stateRoot common.Hash, address common.Address, storageSlot common.Hash, proof *StateProof) (*big.Int, error) {
+	var accountNodes [][]byte
+	if err := rlp.DecodeBytes(proof.AccountProof, &accountNodes); err != nil {
+		return nil, fmt.Errorf("failed to decode account proof: %w", err)
+	}
+	accountProofDB, err := proofNodesToDB(accountNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	accountKey := crypto.Keccak256(address.Bytes())
+	accountRLP, err := trie.VerifyProof(stateRoot, accountKey, accountProofDB)
+	if err != nil {
+		return nil, fmt.Errorf("account proof did not verify against state root %s: %w", stateRoot, err)
+	}
+
+	var account types.StateAccount
+	if err := rlp.DecodeBytes(accountRLP, &account); err != nil {
+		return nil, fmt.Errorf("failed to decode account from proof: %w", err)
+	}
+
+	var storageNodes [][]byte
+	if err := rlp.DecodeBytes(proof.StorageProof, &storageNodes); err != nil {
+		return nil, fmt.Errorf("failed to decode storage proof: %w", err)
+	}
+	storageProofDB, err := proofNodesToDB(storageNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	storageKey := crypto.Keccak256(storageSlot.Bytes())
+	valueRLP, err := trie.VerifyProof(account.Root, storageKey, storageProofDB)
+	if err != nil {
+		return nil, fmt.Errorf("storage proof did not verify against account storage root: %w", err)
+	}
+
+	var value big.Int
+	if err := rlp.DecodeBytes(valueRLP, &value); err != nil {
+		return nil, fmt.Errorf("failed to decode storage value from proof: %w", err)
+	}
+	return &value, nil
+}
+
+// proofNodesToDB loads a flat list of trie nodes into an in-memory
+// key-value store keyed by node hash, the shape trie.VerifyProof expects.
+func proofNodesToDB(nodes [][]byte) (*memorydb.Database, error) {
+	db := memorydb.New()
+	for _, node := range nodes {
+		if len(node) == 0 {
+			return nil, fmt.Errorf("empty proof node")
+		}
+		if err := db.Put(crypto.Keccak256(node), node); err != nil {
+			return nil, fmt.Errorf("failed to load proof node: %w", err)
+		}
+	}
+	return db, nil
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// logChunkBlocks is how many blocks a single FilterLogs call spans.
+// Public RPC providers commonly cap the result set or range of a log
+// query, so wide watch ranges are split into chunks this size.
+const logChunkBlocks = 2000
+
+// subscribeWindowBlocks is the widest "fromBlock..latest" range for
+// which a watch task uses a live SubscribeFilterLogs instead of
+// historical chunked FilterLogs calls.
+const subscribeWindowBlocks = 256
+
+// subscribeCollectionWindow bounds how long a subscription-based watch
+// waits for new matching logs before returning what it has collected.
+const subscribeCollectionWindow = 3 * time.Second
+
+// CounterWatchTask is the event-sourced counterpart to MultiCallTask:
+// instead of a point-in-time read, it collects logs matching an event
+// signature over a block range.
+type CounterWatchTask struct {
+	Address        string          `json:"address"`
+	FromBlock      uint64          `json:"fromBlock"`
+	ToBlock        string          `json:"toBlock"` // a block number as a decimal string, or "latest"
+	EventSignature string          `json:"eventSignature"`
+	EventABI       json.RawMessage `json:"eventAbi"`
+	// Topics are additional indexed-argument filters, hex-encoded,
+	// applied after the mandatory topic0 (the event signature hash).
+	Topics [][]string `json:"topics,omitempty"`
+}
+
+// taskEnvelope is parsed first to read the TaskType discriminator
+// before committing to either MultiCallTask or CounterWatchTask shape.
+// The legacy {counterAddress, blockNumber} payload has no taskType and
+// is always treated as a read.
+type taskEnvelope struct {
+	TaskType string `json:"taskType"`
+}
+
+const (
+	taskTypeRead  = "read"
+	taskTypeWatch = "watch"
+)
+
+// detectTaskType returns the TaskType discriminator from the payload,
+// defaulting to a point-in-time read when absent.
+func detectTaskType(payload []byte) string {
+	var env taskEnvelope
+	_ = json.Unmarshal(payload, &env)
+	if env.TaskType == "" {
+		return taskTypeRead
+	}
+	return env.TaskType
+}
+
+// decodedLog is one matched, decoded event occurrence.
+type decodedLog struct {
+	BlockNumber uint64
+	TxIndex     uint
+	LogIndex    uint
+	Values      []interface{}
+}
+
+// executeWatchTask resolves the task's block range and event ABI,
+// collects matching logs (chunked or live, depending on the range),
+// and decodes each one.
+func (tw *TaskWorker) executeWatchTask(ctx context.Context, task CounterWatchTask) ([]decodedLog, *abi.Event, error) {
+	if !common.IsHexAddress(task.Address) {
+		return nil, nil, fmt.Errorf("invalid contract address %q", task.Address)
+	}
+	if len(task.EventABI) == 0 {
+		return nil, nil, fmt.Errorf("eventAbi is required")
+	}
+
+	parsedABI, err := parseCachedABI(task.EventABI)
+	if err != nil {
+		return nil, nil, err
+	}
+	event, err := resolveEvent(parsedABI, task.EventSignature)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	topics, err := buildTopics(event, task.Topics)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	addr := common.HexToAddress(task.Address)
+
+	head, err := tw.dataSource.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch chain head: %w", err)
+	}
+
+	toBlock, liveTail, err := resolveToBlock(task.ToBlock, head.Number.Uint64())
+	if err != nil {
+		return nil, nil, err
+	}
+	if task.FromBlock > toBlock {
+		return nil, nil, fmt.Errorf("fromBlock %d is after toBlock %d", task.FromBlock, toBlock)
+	}
+
+	var logs []types.Log
+	if liveTail && toBlock-task.FromBlock <= subscribeWindowBlocks {
+		logs, err = tw.collectLiveLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{addr}, Topics: topics}, task.FromBlock, head.Number.Uint64())
+	} else {
+		logs, err = tw.collectHistoricalLogs(ctx, addr, topics, task.FromBlock, toBlock)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decoded := make([]decodedLog, 0, len(logs))
+	for _, l := range logs {
+		values, err := decodeEventLog(parsedABI, event, l)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode log at block %d, index %d: %w", l.BlockNumber, l.Index, err)
+		}
+		decoded = append(decoded, decodedLog{
+			BlockNumber: l.BlockNumber,
+			TxIndex:     l.TxIndex,
+			LogIndex:    l.Index,
+			Values:      values,
+		})
+	}
+
+	return decoded, event, nil
+}
+
+// resolveEvent finds the event in parsedABI, preferring an exact name
+// match on EventSignature's leading identifier (e.g. "Transfer" out of
+// "Transfer(address,address,uint256)") and falling back to the sole
+// event in the ABI fragment.
+func resolveEvent(parsedABI abi.ABI, signature string) (*abi.Event, error) {
+	name := signature
+	if idx := strings.Index(signature, "("); idx >= 0 {
+		name = signature[:idx]
+	}
+	if name != "" {
+		if ev, ok := parsedABI.Events[name]; ok {
+			return &ev, nil
+		}
+	}
+	if len(parsedABI.Events) == 1 {
+		for _, ev := range parsedABI.Events {
+			return &ev, nil
+		}
+	}
+	return nil, fmt.Errorf("event %q not found in supplied ABI", signature)
+}
+
+// buildTopics assembles the eth_getLogs topic filter: topic0 is always
+// the event signature hash, followed by any caller-supplied indexed
+// topic filters.
+func buildTopics(event *abi.Event, extra [][]string) ([][]common.Hash, error) {
+	topics := [][]common.Hash{{event.ID}}
+	for i, group := range extra {
+		hashes := make([]common.Hash, 0, len(group))
+		for _, hex := range group {
+			if hex == "" {
+				continue
+			}
+			hashes = append(hashes, common.HexToHash(hex))
+		}
+		if len(hashes) == 0 {
+			continue
+		}
+		for len(topics) <= i+1 {
+			topics = append(topics, nil)
+		}
+		topics[i+1] = hashes
+	}
+	return topics, nil
+}
+
+// resolveToBlock interprets the task's ToBlock field. "latest" resolves
+// to the current head and reports liveTail=true so the caller can
+// choose a subscription over historical chunking for narrow windows.
+func resolveToBlock(toBlock string, head uint64) (resolved uint64, liveTail bool, err error) {
+	if toBlock == "" || toBlock == "latest" {
+		return head, true, nil
+	}
+	n, err := parseBlockNumber(toBlock)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid toBlock %q: %w", toBlock, err)
+	}
+	return n, false, nil
+}
+
+func parseBlockNumber(s string) (uint64, error) {
+	n := new(big.Int)
+	if _, ok := n.SetString(s, 10); !ok {
+		return 0, fmt.Errorf("not a decimal block number")
+	}
+	if !n.IsUint64() {
+		return 0, fmt.Errorf("block number out of range")
+	}
+	return n.Uint64(), nil
+}
+
+// collectHistoricalLogs walks [fromBlock, toBlock] in logChunkBlocks
+// spans, retrying with a halved chunk size when the upstream endpoint
+// rejects a query for returning too many results — a limit common
+// among public RPC providers.
+func (tw *TaskWorker) collectHistoricalLogs(ctx context.Context, addr common.Address, topics [][]common.Hash, fromBlock, toBlock uint64) ([]types.Log, error) {
+	var all []types.Log
+
+	chunkSize := uint64(logChunkBlocks)
+	for start := fromBlock; start <= toBlock; {
+		end := start + chunkSize - 1
+		if end > toBlock {
+			end = toBlock
+		}
+
+		query := ethereum.FilterQuery{
+			Addresses: []common.Address{addr},
+			Topics:    topics,
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+		}
+
+		logs, err := tw.dataSource.FilterLogs(ctx, query)
+		if err != nil {
+			if isTooManyResultsError(err) && chunkSize > 1 {
+				chunkSize /= 2
+				continue
+			}
+			return nil, fmt.Errorf("failed to filter logs [%d, %d]: %w", start, end, err)
+		}
+
+		all = append(all, logs...)
+		start = end + 1
+	}
+
+	return all, nil
+}
+
+// isTooManyResultsError recognizes the family of error strings public
+// RPC providers return when a log query's range or result set is too
+// large, so the caller can retry with a smaller chunk instead of
+// failing the whole task.
+func isTooManyResultsError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "too many results") ||
+		strings.Contains(msg, "query returned more than") ||
+		strings.Contains(msg, "response size exceeded") ||
+		strings.Contains(msg, "block range") && strings.Contains(msg, "too large")
+}
+
+// collectLiveLogs backfills [fromBlock, head] via FilterLogs, then
+// subscribes for new matching logs and collects whatever arrives
+// within subscribeCollectionWindow, for the narrow "fromBlock..latest"
+// windows where a subscription is cheaper than chunked historical
+// queries. The backfill matters because eth_subscribe("logs", ...)
+// only streams logs mined after the subscription opens — it does not
+// replay [fromBlock, head), so without it a watch task whose fromBlock
+// trails the head would silently drop every already-mined match.
+func (tw *TaskWorker) collectLiveLogs(ctx context.Context, query ethereum.FilterQuery, fromBlock, head uint64) ([]types.Log, error) {
+	var logs []types.Log
+	if fromBlock <= head {
+		backfillQuery := query
+		backfillQuery.FromBlock = new(big.Int).SetUint64(fromBlock)
+		backfillQuery.ToBlock = new(big.Int).SetUint64(head)
+		backfilled, err := tw.dataSource.FilterLogs(ctx, backfillQuery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to backfill logs [%d, %d] before subscribing: %w", fromBlock, head, err)
+		}
+		logs = append(logs, backfilled...)
+	}
+
+	ch := make(chan types.Log, 256)
+	sub, err := tw.dataSource.SubscribeFilterLogs(ctx, query, ch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to logs: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	timeout := time.NewTimer(subscribeCollectionWindow)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case l := <-ch:
+			logs = append(logs, l)
+		case err := <-sub.Err():
+			return nil, fmt.Errorf("log subscription failed: %w", err)
+		case <-timeout.C:
+			return logs, nil
+		case <-ctx.Done():
+			return logs, ctx.Err()
+		}
+	}
+}
+
+// decodeEventLog unpacks both the non-indexed (Data) and indexed
+// (Topics) fields of a log, in the event's declared argument order.
+func decodeEventLog(parsedABI abi.ABI, event *abi.Event, l types.Log) ([]interface{}, error) {
+	values := make([]interface{}, len(event.Inputs))
+
+	nonIndexed := event.Inputs.NonIndexed()
+	unpacked, err := nonIndexed.Unpack(l.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack non-indexed fields: %w", err)
+	}
+
+	var indexedTopics []common.Hash
+	if len(l.Topics) > 1 {
+		indexedTopics = l.Topics[1:]
+	}
+
+	nonIndexedIdx, indexedIdx := 0, 0
+	for i, input := range event.Inputs {
+		if input.Indexed {
+			if indexedIdx >= len(indexedTopics) {
+				return nil, fmt.Errorf("log is missing indexed topic %d", indexedIdx)
+			}
+			decoded, err := decodeIndexedTopic(input.Type, indexedTopics[indexedIdx])
+			if err != nil {
+				return nil, fmt.Errorf("indexed topic %d: %w", indexedIdx, err)
+			}
+			values[i] = decoded
+			indexedIdx++
+			continue
+		}
+		if nonIndexedIdx >= len(unpacked) {
+			return nil, fmt.Errorf("log is missing non-indexed field %d", nonIndexedIdx)
+		}
+		values[i] = unpacked[nonIndexedIdx]
+		nonIndexedIdx++
+	}
+
+	return values, nil
+}
+
+// decodeIndexedTopic recovers an indexed event argument's value from
+// its 32-byte topic. Indexed dynamic types (string, bytes, arrays) are
+// hashed by the EVM before being placed in a topic, so their original
+// value can't be recovered this way; those are returned as the raw
+// topic hash instead. isIndexedValueType identifies exactly this case
+// so packWatchResults knows to re-encode it as bytes32 rather than
+// against the event's declared (and now unsatisfiable) type. uint/int
+// topics are narrowed via bigIntToABIType, the same helper args.go uses,
+// since packWatchResults re-packs them against the event's declared
+// (possibly sub-256-bit) type.
+func decodeIndexedTopic(t abi.Type, topic common.Hash) (interface{}, error) {
+	switch t.T {
+	case abi.AddressTy:
+		return common.BytesToAddress(topic.Bytes()), nil
+	case abi.BoolTy:
+		return topic.Big().Sign() != 0, nil
+	case abi.UintTy:
+		return bigIntToABIType(t, topic.Big())
+	case abi.IntTy:
+		// Signed topics are 256-bit two's complement.
+		v := topic.Big()
+		if topic[0]&0x80 != 0 {
+			v.Sub(v, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return bigIntToABIType(t, v)
+	default:
+		return topic, nil
+	}
+}
+
+// isIndexedValueType reports whether an indexed argument's original
+// type is one decodeIndexedTopic can actually recover from its topic.
+// Every other type (string, bytes, dynamic/fixed arrays, tuples, ...)
+// comes back from decodeIndexedTopic as a raw topic hash, which
+// packWatchResults must encode as bytes32 instead of the declared type.
+func isIndexedValueType(t abi.Type) bool {
+	switch t.T {
+	case abi.AddressTy, abi.BoolTy, abi.UintTy, abi.IntTy:
+		return true
+	default:
+		return false
+	}
+}
+
+// packWatchResults ABI-encodes the collected logs as parallel arrays —
+// block numbers, transaction indexes, log indexes, and the ABI-packed
+// event fields for each match — which Solidity can zip back together
+// without needing a dynamic array of tuples.
+func packWatchResults(event *abi.Event, logs []decodedLog) ([]byte, error) {
+	bytes32Type, err := abi.NewType("bytes32", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Indexed fields whose value can't be recovered from their topic
+	// (decodeIndexedTopic's raw-hash fallback) must be re-encoded as
+	// bytes32, not against the event's declared type, or Pack fails.
+	fieldArgs := make(abi.Arguments, len(event.Inputs))
+	for i, input := range event.Inputs {
+		if input.Indexed && !isIndexedValueType(input.Type) {
+			fieldArgs[i] = abi.Argument{Type: bytes32Type}
+			continue
+		}
+		fieldArgs[i] = abi.Argument{Type: input.Type}
+	}
+
+	blockNumbers := make([]*big.Int, len(logs))
+	txIndexes := make([]*big.Int, len(logs))
+	logIndexes := make([]*big.Int, len(logs))
+	encodedFields := make([][]byte, len(logs))
+
+	for i, l := range logs {
+		blockNumbers[i] = new(big.Int).SetUint64(l.BlockNumber)
+		txIndexes[i] = new(big.Int).SetUint64(uint64(l.TxIndex))
+		logIndexes[i] = new(big.Int).SetUint64(uint64(l.LogIndex))
+
+		packed, err := fieldArgs.Pack(l.Values...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode decoded fields for log %d: %w", i, err)
+		}
+		encodedFields[i] = packed
+	}
+
+	uint256SliceType, err := abi.NewType("uint256[]", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	bytesSliceType, err := abi.NewType("bytes[]", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	args := abi.Arguments{
+		{Type: uint256SliceType},
+		{Type: uint256SliceType},
+		{Type: uint256SliceType},
+		{Type: bytesSliceType},
+	}
+	return args.Pack(blockNumbers, txIndexes, logIndexes, encodedFields)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const transferEventABI = `[{
+	"anonymous": false,
+	"inputs": [
+		{"indexed": true, "name": "from", "type": "address"},
+		{"indexed": true, "name": "to", "type": "address"},
+		{"indexed": false, "name": "value", "type": "uint256"}
+	],
+	"name": "Transfer",
+	"type": "event"
+}]`
+
+func parseTransferEvent(t *testing.T) *abi.Event {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(transferEventABI))
+	if err != nil {
+		t.Fatalf("failed to parse event ABI: %v", err)
+	}
+	event := parsed.Events["Transfer"]
+	return &event
+}
+
+func TestBuildTopics(t *testing.T) {
+	event := parseTransferEvent(t)
+
+	fromFilter := "0x0000000000000000000000000000000000000000000000000000000000000042"[:66]
+	topics, err := buildTopics(event, [][]string{{fromFilter}})
+	if err != nil {
+		t.Fatalf("buildTopics returned unexpected error: %v", err)
+	}
+
+	if len(topics) != 2 {
+		t.Fatalf("expected topic0 plus one filtered topic, got %d slots", len(topics))
+	}
+	if len(topics[0]) != 1 || topics[0][0] != event.ID {
+		t.Fatalf("expected topic0 to be the event signature hash")
+	}
+	if len(topics[1]) != 1 || topics[1][0] != common.HexToHash(fromFilter) {
+		t.Fatalf("expected topic1 to carry the caller-supplied filter")
+	}
+}
+
+func TestBuildTopicsSkipsEmptyGroups(t *testing.T) {
+	event := parseTransferEvent(t)
+
+	topics, err := buildTopics(event, [][]string{{}, {"0x1"}})
+	if err != nil {
+		t.Fatalf("buildTopics returned unexpected error: %v", err)
+	}
+	if len(topics) != 3 {
+		t.Fatalf("expected 3 topic slots (topic0, empty, topic2), got %d", len(topics))
+	}
+	if topics[1] != nil {
+		t.Fatalf("expected an empty filter group to leave its topic slot nil, got %v", topics[1])
+	}
+}
+
+func TestIsIndexedValueTypeAndDecodeIndexedTopic(t *testing.T) {
+	addrType, _ := abi.NewType("address", "", nil)
+	boolType, _ := abi.NewType("bool", "", nil)
+	uintType, _ := abi.NewType("uint256", "", nil)
+	stringType, _ := abi.NewType("string", "", nil)
+	bytesType, _ := abi.NewType("bytes", "", nil)
+
+	cases := []struct {
+		name        string
+		typ         abi.Type
+		recoverable bool
+	}{
+		{"address", addrType, true},
+		{"bool", boolType, true},
+		{"uint256", uintType, true},
+		{"string", stringType, false},
+		{"bytes", bytesType, false},
+	}
+	for _, c := range cases {
+		if got := isIndexedValueType(c.typ); got != c.recoverable {
+			t.Errorf("isIndexedValueType(%s) = %v, want %v", c.name, got, c.recoverable)
+		}
+	}
+
+	addr := common.HexToAddress("0x0000000000000000000000000000000000002a")
+	got, err := decodeIndexedTopic(addrType, common.BytesToHash(addr.Bytes()))
+	if err != nil {
+		t.Fatalf("decodeIndexedTopic returned unexpected error: %v", err)
+	}
+	if got.(common.Address) != addr {
+		t.Errorf("expected decoded address %s, got %v", addr, got)
+	}
+
+	rawTopic := common.HexToHash("0xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	got, err = decodeIndexedTopic(stringType, rawTopic)
+	if err != nil {
+		t.Fatalf("decodeIndexedTopic returned unexpected error: %v", err)
+	}
+	if got.(common.Hash) != rawTopic {
+		t.Errorf("expected unrecoverable indexed string to fall back to the raw topic, got %v", got)
+	}
+}
+
+// TestDecodeIndexedTopicNarrowUint is the regression test for the bug
+// where decodeIndexedTopic returned a bare *big.Int for every indexed
+// uint/int, which packWatchResults then re-packed against the event's
+// declared (possibly sub-256-bit) type; go-ethereum's ABI packer
+// rejects a *big.Int for a uint8 argument.
+func TestDecodeIndexedTopicNarrowUint(t *testing.T) {
+	uint8Type, err := abi.NewType("uint8", "", nil)
+	if err != nil {
+		t.Fatalf("failed to build uint8 type: %v", err)
+	}
+
+	got, err := decodeIndexedTopic(uint8Type, common.BigToHash(big.NewInt(7)))
+	if err != nil {
+		t.Fatalf("decodeIndexedTopic returned unexpected error: %v", err)
+	}
+	value, ok := got.(uint8)
+	if !ok {
+		t.Fatalf("expected decoded value to be uint8, got %T", got)
+	}
+	if value != 7 {
+		t.Fatalf("expected 7, got %d", value)
+	}
+
+	if _, err := (abi.Arguments{{Type: uint8Type}}).Pack(got); err != nil {
+		t.Fatalf("Pack failed for the decoded indexed value: %v", err)
+	}
+}
+
+// TestPackWatchResultsWithUnrecoverableIndexedArg is the regression test
+// for the bug where packWatchResults re-packed an unrecoverable indexed
+// dynamic argument (here, an indexed string) against its original
+// declared type instead of as bytes32, which used to fail ABI encoding.
+func TestPackWatchResultsWithUnrecoverableIndexedArg(t *testing.T) {
+	eventABI := `[{
+		"anonymous": false,
+		"inputs": [{"indexed": true, "name": "label", "type": "string"}],
+		"name": "Labeled",
+		"type": "event"
+	}]`
+	parsed, err := abi.JSON(strings.NewReader(eventABI))
+	if err != nil {
+		t.Fatalf("failed to parse event ABI: %v", err)
+	}
+	event := parsed.Events["Labeled"]
+
+	logs := []decodedLog{{
+		BlockNumber: 1,
+		Values:      []interface{}{common.HexToHash("0x01")},
+	}}
+
+	if _, err := packWatchResults(&event, logs); err != nil {
+		t.Fatalf("packWatchResults returned unexpected error for an unrecoverable indexed arg: %v", err)
+	}
+}
+
+func TestCollectHistoricalLogsRetriesWithSmallerChunkOnTooManyResults(t *testing.T) {
+	var rangesSeen []string
+	ds := &fakeDataSource{
+		filterLogs: func(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+			rangesSeen = append(rangesSeen, fmt.Sprintf("%s-%s", q.FromBlock, q.ToBlock))
+			if len(rangesSeen) == 1 {
+				return nil, fmt.Errorf("query returned more than 10000 results")
+			}
+			return []types.Log{{BlockNumber: q.FromBlock.Uint64()}}, nil
+		},
+	}
+	tw := &TaskWorker{dataSource: ds}
+
+	logs, err := tw.collectHistoricalLogs(context.Background(), common.Address{}, nil, 0, uint64(logChunkBlocks*2))
+	if err != nil {
+		t.Fatalf("collectHistoricalLogs returned unexpected error: %v", err)
+	}
+	if len(logs) == 0 {
+		t.Fatalf("expected logs to be collected after retrying with a smaller chunk")
+	}
+	if len(rangesSeen) < 2 {
+		t.Fatalf("expected at least one retry at a smaller chunk size, got calls: %v", rangesSeen)
+	}
+}
+
+func TestCollectLiveLogsBackfillsBeforeSubscribing(t *testing.T) {
+	backfillLog := types.Log{BlockNumber: 10}
+	subscribeCalled := false
+
+	ds := &fakeDataSource{
+		filterLogs: func(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+			if q.FromBlock.Uint64() != 5 || q.ToBlock.Uint64() != 20 {
+				t.Fatalf("unexpected backfill range [%s, %s]", q.FromBlock, q.ToBlock)
+			}
+			return []types.Log{backfillLog}, nil
+		},
+		subscribeFilterLogs: func(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+			subscribeCalled = true
+			return &fakeSubscription{errCh: make(chan error)}, nil
+		},
+	}
+	tw := &TaskWorker{dataSource: ds}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	logs, _ := tw.collectLiveLogs(ctx, ethereum.FilterQuery{}, 5, 20)
+	if !subscribeCalled {
+		t.Fatalf("expected SubscribeFilterLogs to be called after the backfill")
+	}
+	if len(logs) != 1 || logs[0].BlockNumber != backfillLog.BlockNumber {
+		t.Fatalf("expected the backfilled log to be returned, got %+v", logs)
+	}
+}
+
+func TestCollectLiveLogsSkipsBackfillWhenFromBlockAheadOfHead(t *testing.T) {
+	ds := &fakeDataSource{
+		filterLogs: func(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+			t.Fatalf("did not expect a backfill FilterLogs call when fromBlock is ahead of head")
+			return nil, nil
+		},
+		subscribeFilterLogs: func(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+			return &fakeSubscription{errCh: make(chan error)}, nil
+		},
+	}
+	tw := &TaskWorker{dataSource: ds}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := tw.collectLiveLogs(ctx, ethereum.FilterQuery{}, 25, 20); err == nil {
+		t.Fatalf("expected the context deadline error since no log ever arrived")
+	}
+}
+
+func TestCollectLiveLogsReturnsSubscriptionError(t *testing.T) {
+	subErr := fmt.Errorf("subscription dropped")
+	ds := &fakeDataSource{
+		filterLogs: func(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+			return nil, nil
+		},
+		subscribeFilterLogs: func(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+			errCh := make(chan error, 1)
+			errCh <- subErr
+			return &fakeSubscription{errCh: errCh}, nil
+		},
+	}
+	tw := &TaskWorker{dataSource: ds}
+
+	_, err := tw.collectLiveLogs(context.Background(), ethereum.FilterQuery{}, 5, 20)
+	if err == nil || !strings.Contains(err.Error(), subErr.Error()) {
+		t.Fatalf("expected the subscription error to propagate, got: %v", err)
+	}
+}